@@ -3,12 +3,15 @@ package ast
 import (
 	"bytes"
 	"interpreter/token"
+	"math/big"
 	"strings"
 )
 
 type Node interface {
     TokenLiteral() string
     String() string
+    Pos() token.Position //position of the first character of the node
+    End() token.Position //position immediately after the node
 }
 
 type Statement interface {
@@ -32,6 +35,20 @@ func (p *Program) TokenLiteral() string {
     return p.Statements[0].TokenLiteral()
 }
 
+func (p *Program) Pos() token.Position {
+    if len(p.Statements) == 0 {
+        return token.Position{}
+    }
+    return p.Statements[0].Pos()
+}
+
+func (p *Program) End() token.Position {
+    if len(p.Statements) == 0 {
+        return token.Position{}
+    }
+    return p.Statements[len(p.Statements)-1].End()
+}
+
 func (p *Program) String() string {
     var out bytes.Buffer
 
@@ -50,6 +67,8 @@ type Indentifier struct {
 func (i *Indentifier) expressionNode() {}
 func (i *Indentifier) TokenLiteral() string {return i.Token.Literal}
 func (i *Indentifier) String() string {return i.Value}
+func (i *Indentifier) Pos() token.Position {return i.Token.Pos}
+func (i *Indentifier) End() token.Position {return i.Token.End}
 
 type LetStatemet struct {
     Token token.Token //LET
@@ -59,6 +78,16 @@ type LetStatemet struct {
 
 func (ls *LetStatemet) statementNode() {}
 func (ls *LetStatemet) TokenLiteral() string {return ls.Token.Literal}
+func (ls *LetStatemet) Pos() token.Position {return ls.Token.Pos}
+func (ls *LetStatemet) End() token.Position {
+    if ls.Value != nil {
+        return ls.Value.End()
+    }
+    if ls.Name != nil {
+        return ls.Name.End()
+    }
+    return ls.Token.End
+}
 func (ls *LetStatemet) String() string {
     var out bytes.Buffer
 
@@ -83,6 +112,13 @@ type ReturnStatement struct {
 
 func (rs *ReturnStatement) statementNode() {}
 func (rs *ReturnStatement) TokenLiteral() string {return rs.Token.Literal}
+func (rs *ReturnStatement) Pos() token.Position {return rs.Token.Pos}
+func (rs *ReturnStatement) End() token.Position {
+    if rs.ReturnValue != nil {
+        return rs.ReturnValue.End()
+    }
+    return rs.Token.End
+}
 func (rs *ReturnStatement) String() string {
     var out bytes.Buffer
 
@@ -105,6 +141,13 @@ type ExpressionStatement struct {
 
 func (es *ExpressionStatement) statementNode() {}
 func (es *ExpressionStatement) TokenLiteral() string {return es.Token.Literal}
+func (es *ExpressionStatement) Pos() token.Position {return es.Token.Pos}
+func (es *ExpressionStatement) End() token.Position {
+    if es.Expression != nil {
+        return es.Expression.End()
+    }
+    return es.Token.End
+}
 func (es *ExpressionStatement) String() string {
     if es.Expression != nil {
         return es.Expression.String()
@@ -116,11 +159,25 @@ func (es *ExpressionStatement) String() string {
 type IntegerLiteral struct {
     Token token.Token
     Value int64
+    Big *big.Int //set instead of Value when the literal does not fit in an int64
 }
 
 func (il *IntegerLiteral) expressionNode() {}
 func (il *IntegerLiteral) TokenLiteral() string {return il.Token.Literal}
 func (il *IntegerLiteral) String() string {return il.Token.Literal}
+func (il *IntegerLiteral) Pos() token.Position {return il.Token.Pos}
+func (il *IntegerLiteral) End() token.Position {return il.Token.End}
+
+type FloatLiteral struct {
+    Token token.Token
+    Value float64
+}
+
+func (fl *FloatLiteral) expressionNode() {}
+func (fl *FloatLiteral) TokenLiteral() string {return fl.Token.Literal}
+func (fl *FloatLiteral) String() string {return fl.Token.Literal}
+func (fl *FloatLiteral) Pos() token.Position {return fl.Token.Pos}
+func (fl *FloatLiteral) End() token.Position {return fl.Token.End}
 
 type Boolean struct {
     Token token.Token
@@ -130,6 +187,8 @@ type Boolean struct {
 func (b *Boolean) expressionNode() {}
 func (b *Boolean) TokenLiteral() string {return b.Token.Literal}
 func (b *Boolean) String() string {return b.Token.Literal}
+func (b *Boolean) Pos() token.Position {return b.Token.Pos}
+func (b *Boolean) End() token.Position {return b.Token.End}
 
 type PrefixExpression struct {
     Token token.Token
@@ -139,6 +198,13 @@ type PrefixExpression struct {
 
 func (pe *PrefixExpression) expressionNode() {}
 func (pe *PrefixExpression) TokenLiteral() string {return pe.Token.Literal}
+func (pe *PrefixExpression) Pos() token.Position {return pe.Token.Pos}
+func (pe *PrefixExpression) End() token.Position {
+    if pe.Right != nil {
+        return pe.Right.End()
+    }
+    return pe.Token.End
+}
 func (pe *PrefixExpression) String() string {
     var out bytes.Buffer
 
@@ -159,6 +225,13 @@ type InfixExpression struct {
 
 func (ie *InfixExpression) expressionNode() {}
 func (ie *InfixExpression) TokenLiteral() string {return ie.Token.Literal}
+func (ie *InfixExpression) Pos() token.Position {return ie.Left.Pos()}
+func (ie *InfixExpression) End() token.Position {
+    if ie.Right != nil {
+        return ie.Right.End()
+    }
+    return ie.Token.End
+}
 func (ie *InfixExpression) String() string {
     var out bytes.Buffer
 
@@ -172,11 +245,22 @@ func (ie *InfixExpression) String() string {
 }
 
 type BlockStatement struct {
-    Token token.Token
+    Token token.Token //{
     Statements []Statement
+    Rbrace token.Token
 }
 func (bs *BlockStatement) statementNode() {}
 func (bs *BlockStatement) TokenLiteral() string { return bs.Token.Literal }
+func (bs *BlockStatement) Pos() token.Position { return bs.Token.Pos }
+func (bs *BlockStatement) End() token.Position {
+    if bs.Rbrace.End.IsValid() {
+        return bs.Rbrace.End
+    }
+    if len(bs.Statements) > 0 {
+        return bs.Statements[len(bs.Statements)-1].End()
+    }
+    return bs.Token.End
+}
 func (bs *BlockStatement) String() string {
     var out bytes.Buffer
 
@@ -196,6 +280,16 @@ type IfExpression struct {
 
 func (ie *IfExpression) expressionNode() {}
 func (ie *IfExpression) TokenLiteral() string { return ie.Token.Literal }
+func (ie *IfExpression) Pos() token.Position { return ie.Token.Pos }
+func (ie *IfExpression) End() token.Position {
+    if ie.Alternative != nil {
+        return ie.Alternative.End()
+    }
+    if ie.Consequence != nil {
+        return ie.Consequence.End()
+    }
+    return ie.Token.End
+}
 func (ie *IfExpression) String() string {
     var out bytes.Buffer
 
@@ -211,20 +305,158 @@ func (ie *IfExpression) String() string {
     return out.String()
 }
 
+type WhileStatement struct {
+    Token token.Token //WHILE
+    Condition Expression
+    Body *BlockStatement
+}
+
+func (ws *WhileStatement) statementNode() {}
+func (ws *WhileStatement) TokenLiteral() string { return ws.Token.Literal }
+func (ws *WhileStatement) Pos() token.Position { return ws.Token.Pos }
+func (ws *WhileStatement) End() token.Position {
+    if ws.Body != nil {
+        return ws.Body.End()
+    }
+    return ws.Token.End
+}
+func (ws *WhileStatement) String() string {
+    var out bytes.Buffer
+
+    out.WriteString("while")
+    out.WriteString(ws.Condition.String())
+    out.WriteString(" ")
+    out.WriteString(ws.Body.String())
+
+    return out.String()
+}
+
+// ForStatement is a C-style for loop. Init, Condition and Step are nil when
+// they are left out; a missing condition loops until a break or return.
+type ForStatement struct {
+    Token token.Token //FOR
+    Init Statement
+    Condition Expression
+    Step Expression
+    Body *BlockStatement
+}
+
+func (fs *ForStatement) statementNode() {}
+func (fs *ForStatement) TokenLiteral() string { return fs.Token.Literal }
+func (fs *ForStatement) Pos() token.Position { return fs.Token.Pos }
+func (fs *ForStatement) End() token.Position {
+    if fs.Body != nil {
+        return fs.Body.End()
+    }
+    return fs.Token.End
+}
+func (fs *ForStatement) String() string {
+    var out bytes.Buffer
+
+    out.WriteString("for (")
+    if fs.Init != nil {
+        out.WriteString(strings.TrimSuffix(fs.Init.String(), ";"))
+    }
+    out.WriteString("; ")
+    if fs.Condition != nil {
+        out.WriteString(fs.Condition.String())
+    }
+    out.WriteString("; ")
+    if fs.Step != nil {
+        out.WriteString(fs.Step.String())
+    }
+    out.WriteString(") ")
+    out.WriteString(fs.Body.String())
+
+    return out.String()
+}
+
+// ForInStatement loops over the elements of an iterable object. Key is nil
+// when the loop binds a single variable.
+type ForInStatement struct {
+    Token token.Token //FOR
+    Key *Indentifier
+    Value *Indentifier
+    Iterable Expression
+    Body *BlockStatement
+}
+
+func (fs *ForInStatement) statementNode() {}
+func (fs *ForInStatement) TokenLiteral() string { return fs.Token.Literal }
+func (fs *ForInStatement) Pos() token.Position { return fs.Token.Pos }
+func (fs *ForInStatement) End() token.Position {
+    if fs.Body != nil {
+        return fs.Body.End()
+    }
+    return fs.Token.End
+}
+func (fs *ForInStatement) String() string {
+    var out bytes.Buffer
+
+    out.WriteString("for (")
+    if fs.Key != nil {
+        out.WriteString(fs.Key.String() + ", ")
+    }
+    out.WriteString(fs.Value.String())
+    out.WriteString(" in ")
+    out.WriteString(fs.Iterable.String())
+    out.WriteString(") ")
+    out.WriteString(fs.Body.String())
+
+    return out.String()
+}
+
+type BreakStatement struct {
+    Token token.Token //BREAK
+}
+
+func (bs *BreakStatement) statementNode() {}
+func (bs *BreakStatement) TokenLiteral() string { return bs.Token.Literal }
+func (bs *BreakStatement) Pos() token.Position { return bs.Token.Pos }
+func (bs *BreakStatement) End() token.Position { return bs.Token.End }
+func (bs *BreakStatement) String() string { return "break;" }
+
+type ContinueStatement struct {
+    Token token.Token //CONTINUE
+}
+
+func (cs *ContinueStatement) statementNode() {}
+func (cs *ContinueStatement) TokenLiteral() string { return cs.Token.Literal }
+func (cs *ContinueStatement) Pos() token.Position { return cs.Token.Pos }
+func (cs *ContinueStatement) End() token.Position { return cs.Token.End }
+func (cs *ContinueStatement) String() string { return "continue;" }
+
 type FunctionLiteral struct {
     Token token.Token
     Parameters []*Indentifier
+    Defaults []Expression //default value of each parameter, nil if it has none
+    Rest *Indentifier //collects the remaining arguments, nil if there is none
     Body *BlockStatement
+    Name string //name of the let binding the literal is assigned to, if any
 }
 
 func (fl *FunctionLiteral) expressionNode() {}
 func (fl *FunctionLiteral) TokenLiteral() string {return fl.Token.Literal}
+func (fl *FunctionLiteral) Pos() token.Position {return fl.Token.Pos}
+func (fl *FunctionLiteral) End() token.Position {
+    if fl.Body != nil {
+        return fl.Body.End()
+    }
+    return fl.Token.End
+}
 func (fl *FunctionLiteral) String() string {
     var out bytes.Buffer
 
     params := []string{}
-    for _, p := range fl.Parameters {
-        params = append(params, p.String())
+    for i, p := range fl.Parameters {
+        if i < len(fl.Defaults) && fl.Defaults[i] != nil {
+            params = append(params, p.String() + " = " + fl.Defaults[i].String())
+        } else {
+            params = append(params, p.String())
+        }
+    }
+    if fl.Rest != nil {
+        params = append(params, "..." + fl.Rest.String())
     }
 
     out.WriteString(fl.TokenLiteral())
@@ -237,13 +469,21 @@ func (fl *FunctionLiteral) String() string {
 }
 
 type CallExpression struct {
-    Token token.Token
+    Token token.Token //(
     Function Expression
     Arguments []Expression
+    Rparen token.Token
 }
 
 func (ce *CallExpression) expressionNode() {}
 func (ce *CallExpression) TokenLiteral() string { return ce.Token.Literal }
+func (ce *CallExpression) Pos() token.Position { return ce.Function.Pos() }
+func (ce *CallExpression) End() token.Position {
+    if ce.Rparen.End.IsValid() {
+        return ce.Rparen.End
+    }
+    return ce.Token.End
+}
 func (ce *CallExpression) String() string {
     var out bytes.Buffer
 
@@ -260,6 +500,46 @@ func (ce *CallExpression) String() string {
     return out.String()
 }
 
+// SpreadExpression passes the elements of an iterable as separate arguments
+// of a call.
+type SpreadExpression struct {
+    Token token.Token //...
+    Value Expression
+}
+
+func (se *SpreadExpression) expressionNode() {}
+func (se *SpreadExpression) TokenLiteral() string { return se.Token.Literal }
+func (se *SpreadExpression) Pos() token.Position { return se.Token.Pos }
+func (se *SpreadExpression) End() token.Position {
+    if se.Value != nil {
+        return se.Value.End()
+    }
+    return se.Token.End
+}
+func (se *SpreadExpression) String() string {
+    return "..." + se.Value.String()
+}
+
+// NamedArgument passes an argument of a call by the name of its parameter.
+type NamedArgument struct {
+    Token token.Token //the name
+    Name *Indentifier
+    Value Expression
+}
+
+func (na *NamedArgument) expressionNode() {}
+func (na *NamedArgument) TokenLiteral() string { return na.Token.Literal }
+func (na *NamedArgument) Pos() token.Position { return na.Token.Pos }
+func (na *NamedArgument) End() token.Position {
+    if na.Value != nil {
+        return na.Value.End()
+    }
+    return na.Token.End
+}
+func (na *NamedArgument) String() string {
+    return na.Name.String() + ": " + na.Value.String()
+}
+
 type StringLiteral struct {
     Token token.Token
     Value string
@@ -272,4 +552,129 @@ func (sl *StringLiteral) TokenLiteral() string {
 func (sl *StringLiteral) String() string {
     return sl.Token.Literal
 }
+func (sl *StringLiteral) Pos() token.Position {
+    return sl.Token.Pos
+}
+func (sl *StringLiteral) End() token.Position {
+    return sl.Token.End
+}
+
+
+type ArrayLiteral struct {
+    Token token.Token //[
+    Elements []Expression
+    Rbracket token.Token
+}
+
+func (al *ArrayLiteral) expressionNode() {}
+func (al *ArrayLiteral) TokenLiteral() string { return al.Token.Literal }
+func (al *ArrayLiteral) Pos() token.Position { return al.Token.Pos }
+func (al *ArrayLiteral) End() token.Position {
+    if al.Rbracket.End.IsValid() {
+        return al.Rbracket.End
+    }
+    return al.Token.End
+}
+func (al *ArrayLiteral) String() string {
+    var out bytes.Buffer
+
+    elements := []string{}
+    for _, el := range al.Elements {
+        elements = append(elements, el.String())
+    }
+
+    out.WriteString("[")
+    out.WriteString(strings.Join(elements, ", "))
+    out.WriteString("]")
+
+    return out.String()
+}
+
+// AssignExpression assigns to a variable or an index expression. Operator
+// is "=" or a compound operator like "+=".
+type AssignExpression struct {
+    Token token.Token //the assignment operator
+    Target Expression //*Indentifier or *IndexExpression
+    Operator string
+    Value Expression
+}
+
+func (ae *AssignExpression) expressionNode() {}
+func (ae *AssignExpression) TokenLiteral() string { return ae.Token.Literal }
+func (ae *AssignExpression) Pos() token.Position { return ae.Target.Pos() }
+func (ae *AssignExpression) End() token.Position {
+    if ae.Value != nil {
+        return ae.Value.End()
+    }
+    return ae.Token.End
+}
+func (ae *AssignExpression) String() string {
+    var out bytes.Buffer
+
+    out.WriteString(ae.Target.String())
+    out.WriteString(" " + ae.Operator + " ")
+    if ae.Value != nil {
+        out.WriteString(ae.Value.String())
+    }
+
+    return out.String()
+}
+
+type IndexExpression struct {
+    Token token.Token //[
+    Left Expression
+    Index Expression
+    Rbracket token.Token
+}
+
+func (ie *IndexExpression) expressionNode() {}
+func (ie *IndexExpression) TokenLiteral() string { return ie.Token.Literal }
+func (ie *IndexExpression) Pos() token.Position { return ie.Left.Pos() }
+func (ie *IndexExpression) End() token.Position {
+    if ie.Rbracket.End.IsValid() {
+        return ie.Rbracket.End
+    }
+    return ie.Token.End
+}
+func (ie *IndexExpression) String() string {
+    var out bytes.Buffer
+
+    out.WriteString("(")
+    out.WriteString(ie.Left.String())
+    out.WriteString("[")
+    out.WriteString(ie.Index.String())
+    out.WriteString("])")
+
+    return out.String()
+}
+
+type HashLiteral struct {
+    Token token.Token //{
+    Keys []Expression //in source order, Values[i] belongs to Keys[i]
+    Values []Expression
+    Rbrace token.Token
+}
 
+func (hl *HashLiteral) expressionNode() {}
+func (hl *HashLiteral) TokenLiteral() string { return hl.Token.Literal }
+func (hl *HashLiteral) Pos() token.Position { return hl.Token.Pos }
+func (hl *HashLiteral) End() token.Position {
+    if hl.Rbrace.End.IsValid() {
+        return hl.Rbrace.End
+    }
+    return hl.Token.End
+}
+func (hl *HashLiteral) String() string {
+    var out bytes.Buffer
+
+    pairs := []string{}
+    for i, key := range hl.Keys {
+        pairs = append(pairs, key.String()+": "+hl.Values[i].String())
+    }
+
+    out.WriteString("{")
+    out.WriteString(strings.Join(pairs, ", "))
+    out.WriteString("}")
+
+    return out.String()
+}
@@ -0,0 +1,254 @@
+package code
+
+import (
+	"bytes"
+	"encoding/binary"
+	"fmt"
+)
+
+type Instructions []byte
+
+func (ins Instructions) String() string {
+    var out bytes.Buffer
+
+    i := 0
+    for i < len(ins) {
+        def, err := Lookup(ins[i])
+        if err != nil {
+            fmt.Fprintf(&out, "ERROR: %s\n", err)
+            break
+        }
+
+        operands, read := ReadOperands(def, ins[i+1:])
+        fmt.Fprintf(&out, "%04d %s\n", i, ins.fmtInstruction(def, operands))
+
+        i += 1 + read
+    }
+
+    return out.String()
+}
+
+func (ins Instructions) fmtInstruction(def *Definition, operands []int) string {
+    operandCount := len(def.OperandWidths)
+
+    if len(operands) != operandCount {
+        return fmt.Sprintf("ERROR: operand len %d does not match defined %d\n", len(operands), operandCount)
+    }
+
+    switch operandCount {
+    case 0:
+        return def.Name
+    case 1:
+        return fmt.Sprintf("%s %d", def.Name, operands[0])
+    case 2:
+        return fmt.Sprintf("%s %d %d", def.Name, operands[0], operands[1])
+    }
+
+    return fmt.Sprintf("ERROR: unhandled operandCount for %s\n", def.Name)
+}
+
+type Opcode byte
+
+const (
+    OpConstant Opcode = iota
+    OpPop
+    OpTrue
+    OpFalse
+    OpNull
+
+    OpAdd
+    OpSub
+    OpMul
+    OpDiv
+    OpMod
+    OpPow
+    OpBitAnd
+    OpBitOr
+    OpBitXor
+    OpShiftLeft
+    OpShiftRight
+    OpEqual
+    OpNotEqual
+    OpLessThan
+    OpGreaterThan
+    OpLessEqual
+    OpGreaterEqual
+
+    OpMinus
+    OpBang
+    OpBitNot
+
+    OpJumpNotTruthy
+    OpJump
+    OpJumpNotTruthyOrPop //jumps keeping the condition on the stack, used by && and ||
+    OpJumpTruthyOrPop
+    OpIterator
+    OpIterNext //pushes the loop variables of the next element, or jumps when done
+    OpJumpIfBound //jumps when the caller passed the parameter in the local slot
+    OpLoop //marks the stack pointer a loop's break and continue return to
+    OpLoopEnd
+    OpLoopJump //jumps after dropping what the loop left on the stack, used by break and continue
+
+    OpGetGlobal
+    OpSetGlobal
+    OpAssignGlobal //like OpSetGlobal, but the global must already be defined
+    OpGetLocal
+    OpSetLocal
+    OpGetLocalCell
+    OpLocalCell //pushes a local's cell itself, creating an empty one if the local isn't bound yet
+    OpSetLocalCell
+    OpGetBuiltin
+    OpGetFree
+    OpGetFreeCell
+    OpSetFreeCell
+    OpCurrentClosure
+
+    OpArray
+    OpHash
+    OpIndex
+    OpSetIndex
+    OpDup2
+
+    OpCall
+    OpCallArgs //a call with spread or named arguments
+    OpSpread
+    OpReturnValue
+    OpReturn
+    OpClosure
+)
+
+type Definition struct {
+    Name string
+    OperandWidths []int
+}
+
+var definitions = map[Opcode]*Definition{
+    OpConstant: {"OpConstant", []int{2}},
+    OpPop: {"OpPop", []int{}},
+    OpTrue: {"OpTrue", []int{}},
+    OpFalse: {"OpFalse", []int{}},
+    OpNull: {"OpNull", []int{}},
+
+    OpAdd: {"OpAdd", []int{}},
+    OpSub: {"OpSub", []int{}},
+    OpMul: {"OpMul", []int{}},
+    OpDiv: {"OpDiv", []int{}},
+    OpMod: {"OpMod", []int{}},
+    OpPow: {"OpPow", []int{}},
+    OpBitAnd: {"OpBitAnd", []int{}},
+    OpBitOr: {"OpBitOr", []int{}},
+    OpBitXor: {"OpBitXor", []int{}},
+    OpShiftLeft: {"OpShiftLeft", []int{}},
+    OpShiftRight: {"OpShiftRight", []int{}},
+    OpEqual: {"OpEqual", []int{}},
+    OpNotEqual: {"OpNotEqual", []int{}},
+    OpLessThan: {"OpLessThan", []int{}},
+    OpGreaterThan: {"OpGreaterThan", []int{}},
+    OpLessEqual: {"OpLessEqual", []int{}},
+    OpGreaterEqual: {"OpGreaterEqual", []int{}},
+
+    OpMinus: {"OpMinus", []int{}},
+    OpBang: {"OpBang", []int{}},
+    OpBitNot: {"OpBitNot", []int{}},
+
+    OpJumpNotTruthy: {"OpJumpNotTruthy", []int{2}},
+    OpJump: {"OpJump", []int{2}},
+    OpJumpNotTruthyOrPop: {"OpJumpNotTruthyOrPop", []int{2}},
+    OpJumpTruthyOrPop: {"OpJumpTruthyOrPop", []int{2}},
+    OpIterator: {"OpIterator", []int{}},
+    OpIterNext: {"OpIterNext", []int{2, 1}},
+    OpJumpIfBound: {"OpJumpIfBound", []int{1, 2}},
+    OpLoop: {"OpLoop", []int{}},
+    OpLoopEnd: {"OpLoopEnd", []int{}},
+    OpLoopJump: {"OpLoopJump", []int{2}},
+
+    OpGetGlobal: {"OpGetGlobal", []int{2}},
+    OpSetGlobal: {"OpSetGlobal", []int{2}},
+    OpAssignGlobal: {"OpAssignGlobal", []int{2}},
+    OpGetLocal: {"OpGetLocal", []int{1}},
+    OpSetLocal: {"OpSetLocal", []int{1}},
+    OpGetLocalCell: {"OpGetLocalCell", []int{1}},
+    OpLocalCell: {"OpLocalCell", []int{1}},
+    OpSetLocalCell: {"OpSetLocalCell", []int{1}},
+    OpGetBuiltin: {"OpGetBuiltin", []int{1}},
+    OpGetFree: {"OpGetFree", []int{1}},
+    OpGetFreeCell: {"OpGetFreeCell", []int{1}},
+    OpSetFreeCell: {"OpSetFreeCell", []int{1}},
+    OpCurrentClosure: {"OpCurrentClosure", []int{}},
+
+    OpArray: {"OpArray", []int{2}},
+    OpHash: {"OpHash", []int{2}},
+    OpIndex: {"OpIndex", []int{}},
+    OpSetIndex: {"OpSetIndex", []int{}},
+    OpDup2: {"OpDup2", []int{}},
+
+    OpCall: {"OpCall", []int{1}},
+    OpCallArgs: {"OpCallArgs", []int{1, 1}},
+    OpSpread: {"OpSpread", []int{}},
+    OpReturnValue: {"OpReturnValue", []int{}},
+    OpReturn: {"OpReturn", []int{}},
+    OpClosure: {"OpClosure", []int{2, 1}},
+}
+
+func Lookup(op byte) (*Definition, error) {
+    def, ok := definitions[Opcode(op)]
+    if !ok {
+        return nil, fmt.Errorf("opcode %d undefined", op)
+    }
+
+    return def, nil
+}
+
+func Make(op Opcode, operands ...int) []byte {
+    def, ok := definitions[op]
+    if !ok {
+        return []byte{}
+    }
+
+    instructionLen := 1
+    for _, w := range def.OperandWidths {
+        instructionLen += w
+    }
+
+    instruction := make([]byte, instructionLen)
+    instruction[0] = byte(op)
+
+    offset := 1
+    for i, o := range operands {
+        width := def.OperandWidths[i]
+        switch width {
+        case 2:
+            binary.BigEndian.PutUint16(instruction[offset:], uint16(o))
+        case 1:
+            instruction[offset] = byte(o)
+        }
+        offset += width
+    }
+
+    return instruction
+}
+
+func ReadOperands(def *Definition, ins Instructions) ([]int, int) {
+    operands := make([]int, len(def.OperandWidths))
+    offset := 0
+
+    for i, width := range def.OperandWidths {
+        switch width {
+        case 2:
+            operands[i] = int(ReadUint16(ins[offset:]))
+        case 1:
+            operands[i] = int(ReadUint8(ins[offset:]))
+        }
+        offset += width
+    }
+
+    return operands, offset
+}
+
+func ReadUint16(ins Instructions) uint16 {
+    return binary.BigEndian.Uint16(ins)
+}
+
+func ReadUint8(ins Instructions) uint8 {
+    return uint8(ins[0])
+}
@@ -0,0 +1,1000 @@
+package compiler
+
+import (
+	"fmt"
+	"interpreter/ast"
+	"interpreter/code"
+	"interpreter/object"
+	"strings"
+)
+
+type Compiler struct {
+    constants []object.Object
+
+    symbolTable *SymbolTable
+
+    scopes []CompilationScope
+    scopeIndex int
+
+    err error //first operand that didn't fit in its instruction, see makeInstruction
+}
+
+type CompilationScope struct {
+    instructions code.Instructions
+    lastInstruction EmittedInstruction
+    previousInstruction EmittedInstruction
+
+    boxed map[string]bool //locals kept in cells, see boxedNames
+    bound map[string]bool //names the function binds with let or for-in, see resolve
+    loops []*loopJumps //loops being compiled, innermost last
+}
+
+// loopJumps collects the positions of the jumps emitted for break and
+// continue, which are patched once the loop's end is known.
+type loopJumps struct {
+    breaks []int
+    continues []int
+}
+
+type EmittedInstruction struct {
+    Opcode code.Opcode
+    Position int
+}
+
+type Bytecode struct {
+    Instructions code.Instructions
+    Constants []object.Object
+    GlobalNames []string //names of the global slots, used in runtime errors
+}
+
+func New() *Compiler {
+    symbolTable := NewSymbolTable()
+    for i, def := range object.Builtins {
+        symbolTable.DefineBuiltin(i, def.Name)
+    }
+
+    return &Compiler{
+        constants: []object.Object{},
+        symbolTable: symbolTable,
+        scopes: []CompilationScope{{instructions: code.Instructions{}}},
+        scopeIndex: 0,
+    }
+}
+
+func NewWithState(s *SymbolTable, constants []object.Object) *Compiler {
+    compiler := New()
+    compiler.symbolTable = s
+    compiler.constants = constants
+
+    return compiler
+}
+
+// Compile compiles node. Programs whose constants, variables, arguments or
+// jump targets don't fit in the operands of their instructions are rejected.
+func (c *Compiler) Compile(node ast.Node) error {
+    if err := c.compile(node); err != nil {
+        return err
+    }
+
+    return c.err
+}
+
+func (c *Compiler) compile(node ast.Node) error {
+    switch node := node.(type) {
+    case *ast.Program:
+        for _, s := range node.Statements {
+            if err := c.Compile(s); err != nil {
+                return err
+            }
+        }
+
+    case *ast.ExpressionStatement:
+        if err := c.Compile(node.Expression); err != nil {
+            return err
+        }
+        c.emit(code.OpPop)
+
+    case *ast.BlockStatement:
+        for _, s := range node.Statements {
+            if err := c.Compile(s); err != nil {
+                return err
+            }
+        }
+
+    case *ast.LetStatemet:
+        if err := c.Compile(node.Value); err != nil {
+            return err
+        }
+
+        c.setSymbol(c.define(node.Name.Value))
+
+    case *ast.ReturnStatement:
+        if err := c.Compile(node.ReturnValue); err != nil {
+            return err
+        }
+        c.emit(code.OpReturnValue)
+
+    case *ast.WhileStatement:
+        return c.compileLoop(nil, node.Condition, nil, node.Body)
+
+    case *ast.ForStatement:
+        return c.compileLoop(node.Init, node.Condition, node.Step, node.Body)
+
+    case *ast.ForInStatement:
+        return c.compileForInStatement(node)
+
+    case *ast.BreakStatement, *ast.ContinueStatement:
+        loops := c.scopes[c.scopeIndex].loops
+        if len(loops) == 0 {
+            return fmt.Errorf("%s outside of a loop", node.TokenLiteral())
+        }
+
+        loop := loops[len(loops)-1]
+        pos := c.emit(code.OpLoopJump, 9999)
+        if _, ok := node.(*ast.BreakStatement); ok {
+            loop.breaks = append(loop.breaks, pos)
+        } else {
+            loop.continues = append(loop.continues, pos)
+        }
+
+    case *ast.Indentifier:
+        symbol, ok := c.resolve(node.Value)
+        if !ok {
+            if c.scopeIndex == 0 {
+                return fmt.Errorf("identifier not found: %s", node.Value)
+            }
+
+            // Function bodies may refer to globals that are only defined
+            // after the function literal, so the name is resolved when the
+            // function runs, like the evaluator's enviroment lookup.
+            symbol = c.symbolTable.Global().Define(node.Value)
+        }
+        c.loadSymbol(symbol)
+
+    case *ast.IntegerLiteral:
+        var integer object.Object = &object.Integer{Value: node.Value}
+        if node.Big != nil {
+            integer = &object.BigInt{Value: node.Big}
+        }
+        c.emit(code.OpConstant, c.addConstant(integer))
+
+    case *ast.FloatLiteral:
+        float := &object.Float{Value: node.Value}
+        c.emit(code.OpConstant, c.addConstant(float))
+
+    case *ast.StringLiteral:
+        str := &object.String{Value: node.Value}
+        c.emit(code.OpConstant, c.addConstant(str))
+
+    case *ast.Boolean:
+        if node.Value {
+            c.emit(code.OpTrue)
+        } else {
+            c.emit(code.OpFalse)
+        }
+
+    case *ast.PrefixExpression:
+        if err := c.Compile(node.Right); err != nil {
+            return err
+        }
+
+        op, ok := prefixOperators[node.Operator]
+        if !ok {
+            return fmt.Errorf("unknown operator %s", node.Operator)
+        }
+        c.emit(op)
+
+    case *ast.InfixExpression:
+        if node.Operator == "&&" || node.Operator == "||" {
+            return c.compileLogicalExpression(node)
+        }
+
+        if err := c.Compile(node.Left); err != nil {
+            return err
+        }
+        if err := c.Compile(node.Right); err != nil {
+            return err
+        }
+
+        op, ok := infixOperators[node.Operator]
+        if !ok {
+            return fmt.Errorf("unknown operator %s", node.Operator)
+        }
+        c.emit(op)
+
+    case *ast.AssignExpression:
+        return c.compileAssignExpression(node)
+
+    case *ast.IfExpression:
+        if err := c.Compile(node.Condition); err != nil {
+            return err
+        }
+
+        jumpNotTruthyPos := c.emit(code.OpJumpNotTruthy, 9999)
+
+        if err := c.compileBlockValue(node.Consequence); err != nil {
+            return err
+        }
+
+        jumpPos := c.emit(code.OpJump, 9999)
+        c.changeOperand(jumpNotTruthyPos, len(c.currentInstructions()))
+
+        if node.Alternative == nil {
+            c.emit(code.OpNull)
+        } else {
+            if err := c.compileBlockValue(node.Alternative); err != nil {
+                return err
+            }
+        }
+
+        c.changeOperand(jumpPos, len(c.currentInstructions()))
+
+    case *ast.ArrayLiteral:
+        for _, el := range node.Elements {
+            if err := c.Compile(el); err != nil {
+                return err
+            }
+        }
+        c.emit(code.OpArray, len(node.Elements))
+
+    case *ast.HashLiteral:
+        for i, key := range node.Keys {
+            if err := c.Compile(key); err != nil {
+                return err
+            }
+            if err := c.Compile(node.Values[i]); err != nil {
+                return err
+            }
+        }
+        c.emit(code.OpHash, len(node.Keys)*2)
+
+    case *ast.IndexExpression:
+        if err := c.Compile(node.Left); err != nil {
+            return err
+        }
+        if err := c.Compile(node.Index); err != nil {
+            return err
+        }
+        c.emit(code.OpIndex)
+
+    case *ast.FunctionLiteral:
+        c.enterScope()
+        c.scopes[c.scopeIndex].boxed, c.scopes[c.scopeIndex].bound = boxedNames(node)
+
+        if node.Name != "" {
+            c.symbolTable.DefineFunctionName(node.Name)
+        }
+
+        numDefaults, err := c.compileParameters(node)
+        if err != nil {
+            return err
+        }
+
+        if err := c.Compile(node.Body); err != nil {
+            return err
+        }
+
+        if c.lastInstructionIs(code.OpPop) {
+            c.replaceLastPopWithReturn()
+        }
+        if !c.lastInstructionIs(code.OpReturnValue) {
+            c.emit(code.OpReturn)
+        }
+
+        freeSymbols := c.symbolTable.FreeSymbols
+        freeNames := []string{}
+        for _, s := range freeSymbols {
+            freeNames = append(freeNames, s.Name)
+        }
+        numLocals := c.symbolTable.numDefinitions
+        localNames := c.symbolTable.Names()
+        instructions := c.leaveScope()
+
+        for _, s := range freeSymbols {
+            c.loadFreeSymbol(s)
+        }
+
+        compiledFn := &object.CompiledFunction{
+            Instructions: instructions,
+            NumLocals: numLocals,
+            NumParameters: len(node.Parameters),
+            NumDefaults: numDefaults,
+            Rest: node.Rest != nil,
+            Name: node.Name,
+            LocalNames: localNames,
+            FreeNames: freeNames,
+        }
+        c.emit(code.OpClosure, c.addConstant(compiledFn), len(freeSymbols))
+
+    case *ast.CallExpression:
+        if err := c.Compile(node.Function); err != nil {
+            return err
+        }
+
+        return c.compileCallArguments(node.Arguments)
+
+    default:
+        return fmt.Errorf("cannot compile %T", node)
+    }
+
+    return nil
+}
+
+// compileParameters defines the parameters of a function and compiles its
+// prologue, which evaluates the defaults of the parameters the caller left
+// out and moves captured parameters into cells.
+func (c *Compiler) compileParameters(node *ast.FunctionLiteral) (int, error) {
+    params := []Symbol{}
+    for _, p := range node.Parameters {
+        params = append(params, c.define(p.Value))
+    }
+    if node.Rest != nil {
+        params = append(params, c.define(node.Rest.Value))
+    }
+
+    numDefaults := 0
+    for i, symbol := range params {
+        if i < len(node.Defaults) && node.Defaults[i] != nil {
+            numDefaults++
+
+            jumpPos := c.emit(code.OpJumpIfBound, symbol.Index, 9999)
+            if err := c.Compile(node.Defaults[i]); err != nil {
+                return 0, err
+            }
+            c.emit(code.OpSetLocal, symbol.Index)
+
+            afterDefault := len(c.currentInstructions())
+            c.replaceInstruction(jumpPos, c.makeInstruction(code.OpJumpIfBound, symbol.Index, afterDefault))
+        }
+
+        if symbol.Cell {
+            c.emit(code.OpGetLocal, symbol.Index)
+            c.emit(code.OpSetLocalCell, symbol.Index)
+        }
+    }
+
+    return numDefaults, nil
+}
+
+// compileCallArguments compiles the arguments of a call and the call
+// itself. Calls with spread or named arguments use OpCallArgs, which gets
+// the names of the named arguments as string constants before their values.
+func (c *Compiler) compileCallArguments(args []ast.Expression) error {
+    numPositional, numNamed := 0, 0
+    spread := false
+
+    for _, a := range args {
+        switch a := a.(type) {
+        case *ast.SpreadExpression:
+            if err := c.Compile(a.Value); err != nil {
+                return err
+            }
+            c.emit(code.OpSpread)
+            spread = true
+            numPositional++
+        case *ast.NamedArgument:
+            name := &object.String{Value: a.Name.Value}
+            c.emit(code.OpConstant, c.addConstant(name))
+            if err := c.Compile(a.Value); err != nil {
+                return err
+            }
+            numNamed++
+        default:
+            if err := c.Compile(a); err != nil {
+                return err
+            }
+            numPositional++
+        }
+    }
+
+    if spread || numNamed > 0 {
+        c.emit(code.OpCallArgs, numPositional, numNamed)
+    } else {
+        c.emit(code.OpCall, numPositional)
+    }
+
+    return nil
+}
+
+// compileBlockValue compiles a block that is used as an expression, so it
+// leaves exactly one value on the stack: its last expression or null.
+func (c *Compiler) compileBlockValue(block *ast.BlockStatement) error {
+    if err := c.Compile(block); err != nil {
+        return err
+    }
+
+    if c.lastInstructionIs(code.OpPop) {
+        c.removeLastPop()
+    } else {
+        c.emit(code.OpNull)
+    }
+
+    return nil
+}
+
+// compileLogicalExpression compiles && and || so the right operand is
+// skipped when the left one decides the result, which is then left on the
+// stack as the value of the expression.
+func (c *Compiler) compileLogicalExpression(node *ast.InfixExpression) error {
+    if err := c.Compile(node.Left); err != nil {
+        return err
+    }
+
+    op := code.OpJumpNotTruthyOrPop
+    if node.Operator == "||" {
+        op = code.OpJumpTruthyOrPop
+    }
+    jumpPos := c.emit(op, 9999)
+
+    if err := c.Compile(node.Right); err != nil {
+        return err
+    }
+    c.changeOperand(jumpPos, len(c.currentInstructions()))
+
+    return nil
+}
+
+// compileLoop compiles a while loop, or a for loop with its optional init,
+// condition and step. Like in the evaluator the loop statement evaluates
+// to null, which is popped like the value of an expression statement.
+// OpLoop and OpLoopEnd bracket the loop, so break and continue can drop the
+// operands of the expressions they leave, as in [1, if (x) { break }].
+func (c *Compiler) compileLoop(init ast.Statement, condition ast.Expression, step ast.Expression, body *ast.BlockStatement) error {
+    if init != nil {
+        if err := c.Compile(init); err != nil {
+            return err
+        }
+    }
+    c.emit(code.OpLoop)
+
+    start := len(c.currentInstructions())
+
+    exitPos := -1
+    if condition != nil {
+        if err := c.Compile(condition); err != nil {
+            return err
+        }
+        exitPos = c.emit(code.OpJumpNotTruthy, 9999)
+    }
+
+    loop := c.enterLoop()
+    if err := c.Compile(body); err != nil {
+        return err
+    }
+
+    continuePos := len(c.currentInstructions())
+    if step != nil {
+        if err := c.Compile(step); err != nil {
+            return err
+        }
+        c.emit(code.OpPop)
+    }
+    c.emit(code.OpJump, start)
+
+    end := len(c.currentInstructions())
+    if exitPos >= 0 {
+        c.changeOperand(exitPos, end)
+    }
+    c.leaveLoop(loop, end, continuePos)
+
+    c.emit(code.OpLoopEnd)
+    c.emit(code.OpNull)
+    c.emit(code.OpPop)
+
+    return nil
+}
+
+// compileForInStatement compiles a for-in loop. The iterator stays on the
+// stack while the loop runs and is popped where the loop and its breaks
+// jump to when it ends.
+func (c *Compiler) compileForInStatement(node *ast.ForInStatement) error {
+    if err := c.Compile(node.Iterable); err != nil {
+        return err
+    }
+    c.emit(code.OpIterator)
+    c.emit(code.OpLoop)
+
+    numVars := 1
+    if node.Key != nil {
+        numVars = 2
+    }
+
+    start := len(c.currentInstructions())
+    nextPos := c.emit(code.OpIterNext, 9999, numVars)
+
+    c.setSymbol(c.define(node.Value.Value))
+    if node.Key != nil {
+        c.setSymbol(c.define(node.Key.Value))
+    }
+
+    loop := c.enterLoop()
+    if err := c.Compile(node.Body); err != nil {
+        return err
+    }
+    c.emit(code.OpJump, start)
+
+    end := len(c.currentInstructions())
+    c.replaceInstruction(nextPos, c.makeInstruction(code.OpIterNext, end, numVars))
+    c.leaveLoop(loop, end, start)
+
+    c.emit(code.OpLoopEnd)
+    c.emit(code.OpPop)
+    c.emit(code.OpNull)
+    c.emit(code.OpPop)
+
+    return nil
+}
+
+func (c *Compiler) enterLoop() *loopJumps {
+    loop := &loopJumps{}
+    scope := &c.scopes[c.scopeIndex]
+    scope.loops = append(scope.loops, loop)
+
+    return loop
+}
+
+// leaveLoop patches the loop's break and continue jumps with their targets.
+func (c *Compiler) leaveLoop(loop *loopJumps, breakPos, continuePos int) {
+    scope := &c.scopes[c.scopeIndex]
+    scope.loops = scope.loops[:len(scope.loops)-1]
+
+    for _, pos := range loop.breaks {
+        c.changeOperand(pos, breakPos)
+    }
+    for _, pos := range loop.continues {
+        c.changeOperand(pos, continuePos)
+    }
+}
+
+// compileAssignExpression compiles an assignment so it leaves the assigned
+// value on the stack. Compound assignments load the current value first.
+func (c *Compiler) compileAssignExpression(node *ast.AssignExpression) error {
+    op, compound := infixOperators[strings.TrimSuffix(node.Operator, "=")]
+
+    switch target := node.Target.(type) {
+    case *ast.Indentifier:
+        symbol, err := c.resolveAssignable(target.Value)
+        if err != nil {
+            return err
+        }
+
+        if compound {
+            c.loadSymbol(symbol)
+        }
+        if err := c.Compile(node.Value); err != nil {
+            return err
+        }
+        if compound {
+            c.emit(op)
+        }
+
+        c.storeSymbol(symbol)
+        c.loadSymbol(symbol)
+
+    case *ast.IndexExpression:
+        if err := c.Compile(target.Left); err != nil {
+            return err
+        }
+        if err := c.Compile(target.Index); err != nil {
+            return err
+        }
+
+        if compound {
+            c.emit(code.OpDup2)
+            c.emit(code.OpIndex)
+        }
+        if err := c.Compile(node.Value); err != nil {
+            return err
+        }
+        if compound {
+            c.emit(op)
+        }
+
+        c.emit(code.OpSetIndex)
+
+    default:
+        return fmt.Errorf("cannot assign to %s", node.Target)
+    }
+
+    return nil
+}
+
+func (c *Compiler) resolveAssignable(name string) (Symbol, error) {
+    symbol, ok := c.resolve(name)
+    if !ok {
+        if c.scopeIndex == 0 {
+            return symbol, fmt.Errorf("identifier not found: %s", name)
+        }
+        symbol = c.symbolTable.Global().Define(name)
+    }
+
+    switch {
+    case symbol.Scope == BuiltinScope:
+        return symbol, fmt.Errorf("cannot assign to builtin %s", name)
+    case symbol.Scope == FunctionScope:
+        return symbol, fmt.Errorf("cannot assign to function %s inside its own body", name)
+    case symbol.Scope == FreeScope && !symbol.Cell:
+        return symbol, fmt.Errorf("cannot assign to captured %s", name)
+    }
+
+    return symbol, nil
+}
+
+// resolve looks name up like SymbolTable.Resolve. A name that a nested
+// function uses before an enclosing function binds it is defined in the
+// enclosing function at that point, in a cell, so the closure sees the later
+// binding like it would in the evaluator's enviroment.
+func (c *Compiler) resolve(name string) (Symbol, bool) {
+    if symbol, ok := c.symbolTable.Resolve(name); ok {
+        return symbol, true
+    }
+
+    table := c.symbolTable.Outer
+    for i := c.scopeIndex - 1; i > 0; i-- {
+        if c.scopes[i].bound[name] {
+            table.DefineCell(name)
+            return c.symbolTable.Resolve(name)
+        }
+        table = table.Outer
+    }
+
+    return Symbol{}, false
+}
+
+// boxedNames returns the names that are assigned somewhere in a function
+// and also used by a function nested in it. The function's locals with
+// these names are kept in cells, so the closures see every assignment. A
+// let that binds a name again counts as an assignment too, since the
+// evaluator overwrites the binding in the same enviroment. It also returns
+// the names the function itself binds with parameters, let or for-in.
+func boxedNames(fn *ast.FunctionLiteral) (map[string]bool, map[string]bool) {
+    assigned := map[string]bool{}
+    captured := map[string]bool{}
+    bound := map[string]bool{}
+    for _, p := range fn.Parameters {
+        bound[p.Value] = true
+    }
+    if fn.Rest != nil {
+        bound[fn.Rest.Value] = true
+    }
+    for _, d := range fn.Defaults {
+        if d != nil {
+            collectNames(d, false, assigned, captured, bound)
+        }
+    }
+    collectNames(fn.Body, false, assigned, captured, bound)
+
+    boxed := map[string]bool{}
+    for name := range assigned {
+        if captured[name] {
+            boxed[name] = true
+        }
+    }
+
+    return boxed, bound
+}
+
+func collectNames(node ast.Node, nested bool, assigned, captured, bound map[string]bool) {
+    visit := func(n ast.Node) {
+        collectNames(n, nested, assigned, captured, bound)
+    }
+    // visitLoopBody treats the lets in a loop body as assignments, since
+    // they bind their names again on every iteration.
+    visitLoopBody := func(body *ast.BlockStatement) {
+        before := map[string]bool{}
+        for name := range bound {
+            before[name] = true
+        }
+        visit(body)
+        for name := range bound {
+            if !before[name] {
+                assigned[name] = true
+            }
+        }
+    }
+
+    switch node := node.(type) {
+    case *ast.BlockStatement:
+        for _, s := range node.Statements {
+            visit(s)
+        }
+    case *ast.ExpressionStatement:
+        visit(node.Expression)
+    case *ast.LetStatemet:
+        if !nested {
+            if bound[node.Name.Value] {
+                assigned[node.Name.Value] = true
+            }
+            bound[node.Name.Value] = true
+        }
+        visit(node.Value)
+    case *ast.ReturnStatement:
+        visit(node.ReturnValue)
+    case *ast.WhileStatement:
+        visit(node.Condition)
+        visitLoopBody(node.Body)
+    case *ast.ForStatement:
+        if node.Init != nil {
+            visit(node.Init)
+        }
+        visit(node.Condition)
+        visit(node.Step)
+        visitLoopBody(node.Body)
+    case *ast.ForInStatement:
+        // the loop variables get a new value on every iteration
+        assigned[node.Value.Value] = true
+        if node.Key != nil {
+            assigned[node.Key.Value] = true
+        }
+        if !nested {
+            bound[node.Value.Value] = true
+            if node.Key != nil {
+                bound[node.Key.Value] = true
+            }
+        }
+        visit(node.Iterable)
+        visitLoopBody(node.Body)
+    case *ast.Indentifier:
+        if nested {
+            captured[node.Value] = true
+        }
+    case *ast.AssignExpression:
+        if ident, ok := node.Target.(*ast.Indentifier); ok {
+            assigned[ident.Value] = true
+        }
+        visit(node.Target)
+        visit(node.Value)
+    case *ast.PrefixExpression:
+        visit(node.Right)
+    case *ast.InfixExpression:
+        visit(node.Left)
+        visit(node.Right)
+    case *ast.IfExpression:
+        visit(node.Condition)
+        visit(node.Consequence)
+        if node.Alternative != nil {
+            visit(node.Alternative)
+        }
+    case *ast.ArrayLiteral:
+        for _, el := range node.Elements {
+            visit(el)
+        }
+    case *ast.HashLiteral:
+        for i, key := range node.Keys {
+            visit(key)
+            visit(node.Values[i])
+        }
+    case *ast.IndexExpression:
+        visit(node.Left)
+        visit(node.Index)
+    case *ast.CallExpression:
+        visit(node.Function)
+        for _, a := range node.Arguments {
+            visit(a)
+        }
+    case *ast.SpreadExpression:
+        visit(node.Value)
+    case *ast.NamedArgument:
+        visit(node.Value)
+    case *ast.FunctionLiteral:
+        for _, d := range node.Defaults {
+            if d != nil {
+                collectNames(d, true, assigned, captured, bound)
+            }
+        }
+        collectNames(node.Body, true, assigned, captured, bound)
+    }
+}
+
+var prefixOperators = map[string]code.Opcode{
+    "-": code.OpMinus,
+    "!": code.OpBang,
+    "~": code.OpBitNot,
+}
+
+var infixOperators = map[string]code.Opcode{
+    "+": code.OpAdd,
+    "-": code.OpSub,
+    "*": code.OpMul,
+    "/": code.OpDiv,
+    "%": code.OpMod,
+    "**": code.OpPow,
+    "&": code.OpBitAnd,
+    "|": code.OpBitOr,
+    "^": code.OpBitXor,
+    "<<": code.OpShiftLeft,
+    ">>": code.OpShiftRight,
+    "==": code.OpEqual,
+    "!=": code.OpNotEqual,
+    "<": code.OpLessThan,
+    ">": code.OpGreaterThan,
+    "<=": code.OpLessEqual,
+    ">=": code.OpGreaterEqual,
+}
+
+func (c *Compiler) Bytecode() *Bytecode {
+    return &Bytecode{
+        Instructions: c.currentInstructions(),
+        Constants: c.constants,
+        GlobalNames: c.symbolTable.Global().Names(),
+    }
+}
+
+func (c *Compiler) addConstant(obj object.Object) int {
+    c.constants = append(c.constants, obj)
+    return len(c.constants) - 1
+}
+
+// makeInstruction is code.Make, recording an error in c.err when an operand
+// doesn't fit in its width instead of truncating it.
+func (c *Compiler) makeInstruction(op code.Opcode, operands ...int) []byte {
+    def, err := code.Lookup(byte(op))
+    if err == nil && c.err == nil {
+        for i, width := range def.OperandWidths {
+            max := 1<<(8*width) - 1
+            if i < len(operands) && (operands[i] < 0 || operands[i] > max) {
+                c.err = fmt.Errorf("program too large: %s operand %d exceeds %d", def.Name, operands[i], max)
+                break
+            }
+        }
+    }
+
+    return code.Make(op, operands...)
+}
+
+func (c *Compiler) emit(op code.Opcode, operands ...int) int {
+    ins := c.makeInstruction(op, operands...)
+    pos := c.addInstruction(ins)
+
+    c.setLastInstruction(op, pos)
+
+    return pos
+}
+
+func (c *Compiler) addInstruction(ins []byte) int {
+    posNewInstruction := len(c.currentInstructions())
+    c.scopes[c.scopeIndex].instructions = append(c.currentInstructions(), ins...)
+
+    return posNewInstruction
+}
+
+func (c *Compiler) setLastInstruction(op code.Opcode, pos int) {
+    previous := c.scopes[c.scopeIndex].lastInstruction
+    last := EmittedInstruction{Opcode: op, Position: pos}
+
+    c.scopes[c.scopeIndex].previousInstruction = previous
+    c.scopes[c.scopeIndex].lastInstruction = last
+}
+
+func (c *Compiler) currentInstructions() code.Instructions {
+    return c.scopes[c.scopeIndex].instructions
+}
+
+func (c *Compiler) lastInstructionIs(op code.Opcode) bool {
+    if len(c.currentInstructions()) == 0 {
+        return false
+    }
+
+    return c.scopes[c.scopeIndex].lastInstruction.Opcode == op
+}
+
+func (c *Compiler) removeLastPop() {
+    last := c.scopes[c.scopeIndex].lastInstruction
+    previous := c.scopes[c.scopeIndex].previousInstruction
+
+    c.scopes[c.scopeIndex].instructions = c.currentInstructions()[:last.Position]
+    c.scopes[c.scopeIndex].lastInstruction = previous
+}
+
+func (c *Compiler) replaceLastPopWithReturn() {
+    lastPos := c.scopes[c.scopeIndex].lastInstruction.Position
+    c.replaceInstruction(lastPos, c.makeInstruction(code.OpReturnValue))
+
+    c.scopes[c.scopeIndex].lastInstruction.Opcode = code.OpReturnValue
+}
+
+func (c *Compiler) replaceInstruction(pos int, newInstruction []byte) {
+    ins := c.currentInstructions()
+
+    for i := 0; i < len(newInstruction); i++ {
+        ins[pos+i] = newInstruction[i]
+    }
+}
+
+func (c *Compiler) changeOperand(opPos int, operand int) {
+    op := code.Opcode(c.currentInstructions()[opPos])
+    newInstruction := c.makeInstruction(op, operand)
+
+    c.replaceInstruction(opPos, newInstruction)
+}
+
+func (c *Compiler) enterScope() {
+    scope := CompilationScope{instructions: code.Instructions{}}
+    c.scopes = append(c.scopes, scope)
+    c.scopeIndex++
+
+    c.symbolTable = NewEnclosedSymbolTable(c.symbolTable)
+}
+
+func (c *Compiler) leaveScope() code.Instructions {
+    instructions := c.currentInstructions()
+
+    c.scopes = c.scopes[:len(c.scopes)-1]
+    c.scopeIndex--
+
+    c.symbolTable = c.symbolTable.Outer
+
+    return instructions
+}
+
+func (c *Compiler) define(name string) Symbol {
+    if c.scopes[c.scopeIndex].boxed[name] {
+        return c.symbolTable.DefineCell(name)
+    }
+
+    return c.symbolTable.Define(name)
+}
+
+func (c *Compiler) loadSymbol(s Symbol) {
+    switch s.Scope {
+    case GlobalScope:
+        c.emit(code.OpGetGlobal, s.Index)
+    case LocalScope:
+        if s.Cell {
+            c.emit(code.OpGetLocalCell, s.Index)
+        } else {
+            c.emit(code.OpGetLocal, s.Index)
+        }
+    case BuiltinScope:
+        c.emit(code.OpGetBuiltin, s.Index)
+    case FreeScope:
+        if s.Cell {
+            c.emit(code.OpGetFreeCell, s.Index)
+        } else {
+            c.emit(code.OpGetFree, s.Index)
+        }
+    case FunctionScope:
+        c.emit(code.OpCurrentClosure)
+    }
+}
+
+// loadFreeSymbol pushes a variable captured by a closure. Cells are pushed
+// themselves rather than their values, so the closure shares them.
+func (c *Compiler) loadFreeSymbol(s Symbol) {
+    switch {
+    case s.Scope == LocalScope && s.Cell:
+        c.emit(code.OpLocalCell, s.Index)
+    case s.Scope == LocalScope:
+        c.emit(code.OpGetLocal, s.Index)
+    case s.Scope == FreeScope:
+        c.emit(code.OpGetFree, s.Index)
+    default:
+        c.loadSymbol(s)
+    }
+}
+
+// setSymbol stores the value on top of the stack in a variable that was
+// just defined, like a let does.
+func (c *Compiler) setSymbol(s Symbol) {
+    switch {
+    case s.Scope == GlobalScope:
+        c.emit(code.OpSetGlobal, s.Index)
+    case s.Cell:
+        c.emit(code.OpSetLocalCell, s.Index)
+    default:
+        c.emit(code.OpSetLocal, s.Index)
+    }
+}
+
+func (c *Compiler) storeSymbol(s Symbol) {
+    switch {
+    case s.Scope == GlobalScope:
+        c.emit(code.OpAssignGlobal, s.Index)
+    case s.Scope == LocalScope && s.Cell:
+        c.emit(code.OpSetLocalCell, s.Index)
+    case s.Scope == LocalScope:
+        c.emit(code.OpSetLocal, s.Index)
+    case s.Scope == FreeScope:
+        c.emit(code.OpSetFreeCell, s.Index)
+    }
+}
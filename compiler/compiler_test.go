@@ -0,0 +1,512 @@
+package compiler
+
+import (
+	"fmt"
+	"interpreter/code"
+	"interpreter/lexer"
+	"interpreter/object"
+	"interpreter/parser"
+	"strconv"
+	"strings"
+	"testing"
+)
+
+type compilerTestCase struct {
+    input string
+    expectedConstants []interface{}
+    expectedInstructions []code.Instructions
+}
+
+func TestIntegerArithmetic(t *testing.T) {
+    tests := []compilerTestCase{
+        {
+            input: "1 + 2",
+            expectedConstants: []interface{}{1, 2},
+            expectedInstructions: []code.Instructions{
+                code.Make(code.OpConstant, 0),
+                code.Make(code.OpConstant, 1),
+                code.Make(code.OpAdd),
+                code.Make(code.OpPop),
+            },
+        },
+        {
+            input: "1 < 2",
+            expectedConstants: []interface{}{1, 2},
+            expectedInstructions: []code.Instructions{
+                code.Make(code.OpConstant, 0),
+                code.Make(code.OpConstant, 1),
+                code.Make(code.OpLessThan),
+                code.Make(code.OpPop),
+            },
+        },
+        {
+            input: "-1",
+            expectedConstants: []interface{}{1},
+            expectedInstructions: []code.Instructions{
+                code.Make(code.OpConstant, 0),
+                code.Make(code.OpMinus),
+                code.Make(code.OpPop),
+            },
+        },
+    }
+
+    runCompilerTests(t, tests)
+}
+
+func TestConditionals(t *testing.T) {
+    tests := []compilerTestCase{
+        {
+            input: "if (true) { 10 }; 3333;",
+            expectedConstants: []interface{}{10, 3333},
+            expectedInstructions: []code.Instructions{
+                // 0000
+                code.Make(code.OpTrue),
+                // 0001
+                code.Make(code.OpJumpNotTruthy, 10),
+                // 0004
+                code.Make(code.OpConstant, 0),
+                // 0007
+                code.Make(code.OpJump, 11),
+                // 0010
+                code.Make(code.OpNull),
+                // 0011
+                code.Make(code.OpPop),
+                // 0012
+                code.Make(code.OpConstant, 1),
+                // 0015
+                code.Make(code.OpPop),
+            },
+        },
+    }
+
+    runCompilerTests(t, tests)
+}
+
+func TestLogicalOperators(t *testing.T) {
+    tests := []compilerTestCase{
+        {
+            input: "true && false; 1",
+            expectedConstants: []interface{}{1},
+            expectedInstructions: []code.Instructions{
+                // 0000
+                code.Make(code.OpTrue),
+                // 0001
+                code.Make(code.OpJumpNotTruthyOrPop, 5),
+                // 0004
+                code.Make(code.OpFalse),
+                // 0005
+                code.Make(code.OpPop),
+                // 0006
+                code.Make(code.OpConstant, 0),
+                // 0009
+                code.Make(code.OpPop),
+            },
+        },
+        {
+            input: "false || 1 >= 2",
+            expectedConstants: []interface{}{1, 2},
+            expectedInstructions: []code.Instructions{
+                code.Make(code.OpFalse),
+                code.Make(code.OpJumpTruthyOrPop, 11),
+                code.Make(code.OpConstant, 0),
+                code.Make(code.OpConstant, 1),
+                code.Make(code.OpGreaterEqual),
+                code.Make(code.OpPop),
+            },
+        },
+    }
+
+    runCompilerTests(t, tests)
+}
+
+func TestLoops(t *testing.T) {
+    tests := []compilerTestCase{
+        {
+            input: "while (true) { break; continue; 1 }",
+            expectedConstants: []interface{}{1},
+            expectedInstructions: []code.Instructions{
+                // 0000
+                code.Make(code.OpLoop),
+                // 0001
+                code.Make(code.OpTrue),
+                // 0002
+                code.Make(code.OpJumpNotTruthy, 18),
+                // 0005
+                code.Make(code.OpLoopJump, 18),
+                // 0008
+                code.Make(code.OpLoopJump, 15),
+                // 0011
+                code.Make(code.OpConstant, 0),
+                // 0014
+                code.Make(code.OpPop),
+                // 0015
+                code.Make(code.OpJump, 1),
+                // 0018
+                code.Make(code.OpLoopEnd),
+                // 0019
+                code.Make(code.OpNull),
+                // 0020
+                code.Make(code.OpPop),
+            },
+        },
+        {
+            input: "for (let i = 0; ; i += 1) { continue }",
+            expectedConstants: []interface{}{0, 1},
+            expectedInstructions: []code.Instructions{
+                // 0000
+                code.Make(code.OpConstant, 0),
+                // 0003
+                code.Make(code.OpSetGlobal, 0),
+                // 0006
+                code.Make(code.OpLoop),
+                // 0007
+                code.Make(code.OpLoopJump, 10),
+                // 0010
+                code.Make(code.OpGetGlobal, 0),
+                // 0013
+                code.Make(code.OpConstant, 1),
+                // 0016
+                code.Make(code.OpAdd),
+                // 0017
+                code.Make(code.OpAssignGlobal, 0),
+                // 0020
+                code.Make(code.OpGetGlobal, 0),
+                // 0023
+                code.Make(code.OpPop),
+                // 0024
+                code.Make(code.OpJump, 7),
+                // 0027
+                code.Make(code.OpLoopEnd),
+                // 0028
+                code.Make(code.OpNull),
+                // 0029
+                code.Make(code.OpPop),
+            },
+        },
+    }
+
+    runCompilerTests(t, tests)
+}
+
+func TestForInLoops(t *testing.T) {
+    tests := []compilerTestCase{
+        {
+            input: "for (k, v in []) { break }",
+            expectedConstants: []interface{}{},
+            expectedInstructions: []code.Instructions{
+                // 0000
+                code.Make(code.OpArray, 0),
+                // 0003
+                code.Make(code.OpIterator),
+                // 0004
+                code.Make(code.OpLoop),
+                // 0005
+                code.Make(code.OpIterNext, 21, 2),
+                // 0009
+                code.Make(code.OpSetGlobal, 0),
+                // 0012
+                code.Make(code.OpSetGlobal, 1),
+                // 0015
+                code.Make(code.OpLoopJump, 21),
+                // 0018
+                code.Make(code.OpJump, 5),
+                // 0021
+                code.Make(code.OpLoopEnd),
+                // 0022
+                code.Make(code.OpPop),
+                // 0023
+                code.Make(code.OpNull),
+                // 0024
+                code.Make(code.OpPop),
+            },
+        },
+    }
+
+    runCompilerTests(t, tests)
+}
+
+func TestGlobalLetStatements(t *testing.T) {
+    tests := []compilerTestCase{
+        {
+            input: "let one = 1; let two = one; let one = 3;",
+            expectedConstants: []interface{}{1, 3},
+            expectedInstructions: []code.Instructions{
+                code.Make(code.OpConstant, 0),
+                code.Make(code.OpSetGlobal, 0),
+                code.Make(code.OpGetGlobal, 0),
+                code.Make(code.OpSetGlobal, 1),
+                code.Make(code.OpConstant, 1),
+                code.Make(code.OpSetGlobal, 0),
+            },
+        },
+    }
+
+    runCompilerTests(t, tests)
+}
+
+func TestFunctions(t *testing.T) {
+    tests := []compilerTestCase{
+        {
+            input: "fn(a) { let b = a; b }",
+            expectedConstants: []interface{}{
+                []code.Instructions{
+                    code.Make(code.OpGetLocal, 0),
+                    code.Make(code.OpSetLocal, 1),
+                    code.Make(code.OpGetLocal, 1),
+                    code.Make(code.OpReturnValue),
+                },
+            },
+            expectedInstructions: []code.Instructions{
+                code.Make(code.OpClosure, 0, 0),
+                code.Make(code.OpPop),
+            },
+        },
+        {
+            input: "fn() { }",
+            expectedConstants: []interface{}{
+                []code.Instructions{
+                    code.Make(code.OpReturn),
+                },
+            },
+            expectedInstructions: []code.Instructions{
+                code.Make(code.OpClosure, 0, 0),
+                code.Make(code.OpPop),
+            },
+        },
+        {
+            input: "fn(a) { fn(b) { a + b } }",
+            expectedConstants: []interface{}{
+                []code.Instructions{
+                    code.Make(code.OpGetFree, 0),
+                    code.Make(code.OpGetLocal, 0),
+                    code.Make(code.OpAdd),
+                    code.Make(code.OpReturnValue),
+                },
+                []code.Instructions{
+                    code.Make(code.OpGetLocal, 0),
+                    code.Make(code.OpClosure, 0, 1),
+                    code.Make(code.OpReturnValue),
+                },
+            },
+            expectedInstructions: []code.Instructions{
+                code.Make(code.OpClosure, 1, 0),
+                code.Make(code.OpPop),
+            },
+        },
+    }
+
+    runCompilerTests(t, tests)
+}
+
+func TestAssignExpressions(t *testing.T) {
+    tests := []compilerTestCase{
+        {
+            input: "let x = 1; x += 2",
+            expectedConstants: []interface{}{1, 2},
+            expectedInstructions: []code.Instructions{
+                code.Make(code.OpConstant, 0),
+                code.Make(code.OpSetGlobal, 0),
+                code.Make(code.OpGetGlobal, 0),
+                code.Make(code.OpConstant, 1),
+                code.Make(code.OpAdd),
+                code.Make(code.OpAssignGlobal, 0),
+                code.Make(code.OpGetGlobal, 0),
+                code.Make(code.OpPop),
+            },
+        },
+        {
+            input: "fn(a) { fn() { a = 1 } }",
+            expectedConstants: []interface{}{
+                1,
+                []code.Instructions{
+                    code.Make(code.OpConstant, 0),
+                    code.Make(code.OpSetFreeCell, 0),
+                    code.Make(code.OpGetFreeCell, 0),
+                    code.Make(code.OpReturnValue),
+                },
+                []code.Instructions{
+                    code.Make(code.OpGetLocal, 0),
+                    code.Make(code.OpSetLocalCell, 0),
+                    code.Make(code.OpLocalCell, 0),
+                    code.Make(code.OpClosure, 1, 1),
+                    code.Make(code.OpReturnValue),
+                },
+            },
+            expectedInstructions: []code.Instructions{
+                code.Make(code.OpClosure, 2, 0),
+                code.Make(code.OpPop),
+            },
+        },
+        {
+            input: "let a = [1]; a[0] *= 2",
+            expectedConstants: []interface{}{1, 0, 2},
+            expectedInstructions: []code.Instructions{
+                code.Make(code.OpConstant, 0),
+                code.Make(code.OpArray, 1),
+                code.Make(code.OpSetGlobal, 0),
+                code.Make(code.OpGetGlobal, 0),
+                code.Make(code.OpConstant, 1),
+                code.Make(code.OpDup2),
+                code.Make(code.OpIndex),
+                code.Make(code.OpConstant, 2),
+                code.Make(code.OpMul),
+                code.Make(code.OpSetIndex),
+                code.Make(code.OpPop),
+            },
+        },
+    }
+
+    runCompilerTests(t, tests)
+}
+
+func TestBuiltins(t *testing.T) {
+    tests := []compilerTestCase{
+        {
+            input: "len([]);",
+            expectedConstants: []interface{}{},
+            expectedInstructions: []code.Instructions{
+                code.Make(code.OpGetBuiltin, 0),
+                code.Make(code.OpArray, 0),
+                code.Make(code.OpCall, 1),
+                code.Make(code.OpPop),
+            },
+        },
+    }
+
+    runCompilerTests(t, tests)
+}
+
+func TestUndefinedIdentifier(t *testing.T) {
+    program := parser.New(lexer.New("foobar")).ParseProgram()
+
+    err := New().Compile(program)
+    if err == nil || err.Error() != "identifier not found: foobar" {
+        t.Errorf("expected undefined identifier error, got %v", err)
+    }
+}
+
+func TestOperandLimits(t *testing.T) {
+    repeat := func(n int, sep string, part func(i int) string) string {
+        parts := []string{}
+        for i := 0; i < n; i++ {
+            parts = append(parts, part(i))
+        }
+        return strings.Join(parts, sep)
+    }
+    number := func(i int) string { return strconv.Itoa(i) }
+    let := func(i int) string { return fmt.Sprintf("let v%d = %d", i, i) }
+    builtin := func(i int) string { return "len" }
+
+    tests := []struct {
+        input string
+        expectedError string
+    }{
+        {"fn() { " + repeat(257, "; ", let) + " }", "program too large: OpSetLocal operand 256 exceeds 255"},
+        {"len(" + repeat(256, ", ", number) + ")", "program too large: OpCall operand 256 exceeds 255"},
+        {repeat(65537, "; ", number), "program too large: OpConstant operand 65536 exceeds 65535"},
+        {"if (true) { " + repeat(30000, "; ", builtin) + " }", "program too large: OpJumpNotTruthy operand 90006 exceeds 65535"},
+    }
+
+    for _, tt := range tests {
+        program := parser.New(lexer.New(tt.input)).ParseProgram()
+
+        err := New().Compile(program)
+        if err == nil || err.Error() != tt.expectedError {
+            t.Errorf("expected error %q, got %v", tt.expectedError, err)
+        }
+    }
+}
+
+func TestResolveFree(t *testing.T) {
+    global := NewSymbolTable()
+    global.Define("a")
+
+    firstLocal := NewEnclosedSymbolTable(global)
+    firstLocal.Define("c")
+
+    secondLocal := NewEnclosedSymbolTable(firstLocal)
+    secondLocal.Define("e")
+
+    expected := []Symbol{
+        {Name: "a", Scope: GlobalScope, Index: 0},
+        {Name: "c", Scope: FreeScope, Index: 0},
+        {Name: "e", Scope: LocalScope, Index: 0},
+    }
+
+    for _, sym := range expected {
+        result, ok := secondLocal.Resolve(sym.Name)
+        if !ok {
+            t.Errorf("name %s not resolvable", sym.Name)
+            continue
+        }
+        if result != sym {
+            t.Errorf("expected %s to resolve to %+v, got=%+v", sym.Name, sym, result)
+        }
+    }
+
+    if len(secondLocal.FreeSymbols) != 1 || secondLocal.FreeSymbols[0].Name != "c" {
+        t.Errorf("wrong free symbols. got=%+v", secondLocal.FreeSymbols)
+    }
+}
+
+func runCompilerTests(t *testing.T, tests []compilerTestCase) {
+    t.Helper()
+
+    for _, tt := range tests {
+        program := parser.New(lexer.New(tt.input)).ParseProgram()
+
+        compiler := New()
+        if err := compiler.Compile(program); err != nil {
+            t.Fatalf("compiler error: %s", err)
+        }
+
+        bytecode := compiler.Bytecode()
+
+        testInstructions(t, tt.input, tt.expectedInstructions, bytecode.Instructions)
+        testConstants(t, tt.input, tt.expectedConstants, bytecode.Constants)
+    }
+}
+
+func concatInstructions(s []code.Instructions) code.Instructions {
+    out := code.Instructions{}
+    for _, ins := range s {
+        out = append(out, ins...)
+    }
+
+    return out
+}
+
+func testInstructions(t *testing.T, input string, expected []code.Instructions, actual code.Instructions) {
+    t.Helper()
+
+    concatted := concatInstructions(expected)
+    if concatted.String() != actual.String() {
+        t.Errorf("%q: wrong instructions.\nwant=\n%s\ngot=\n%s", input, concatted, actual)
+    }
+}
+
+func testConstants(t *testing.T, input string, expected []interface{}, actual []object.Object) {
+    t.Helper()
+
+    if len(expected) != len(actual) {
+        t.Errorf("%q: wrong number of constants. want=%d, got=%d", input, len(expected), len(actual))
+        return
+    }
+
+    for i, constant := range expected {
+        switch constant := constant.(type) {
+        case int:
+            integer, ok := actual[i].(*object.Integer)
+            if !ok || integer.Value != int64(constant) {
+                t.Errorf("%q: constant %d wrong. want=%d, got=%+v", input, i, constant, actual[i])
+            }
+        case []code.Instructions:
+            fn, ok := actual[i].(*object.CompiledFunction)
+            if !ok {
+                t.Errorf("%q: constant %d not a function: %T", input, i, actual[i])
+                continue
+            }
+            testInstructions(t, input, constant, fn.Instructions)
+        }
+    }
+}
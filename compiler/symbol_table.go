@@ -0,0 +1,129 @@
+package compiler
+
+type SymbolScope string
+
+const (
+    GlobalScope SymbolScope = "GLOBAL"
+    LocalScope SymbolScope = "LOCAL"
+    BuiltinScope SymbolScope = "BUILTIN"
+    FreeScope SymbolScope = "FREE"
+    FunctionScope SymbolScope = "FUNCTION"
+)
+
+type Symbol struct {
+    Name string
+    Scope SymbolScope
+    Index int
+    Cell bool //the local lives in an object.Cell shared with closures
+}
+
+type SymbolTable struct {
+    Outer *SymbolTable
+
+    store map[string]Symbol
+    numDefinitions int
+
+    FreeSymbols []Symbol
+}
+
+func NewSymbolTable() *SymbolTable {
+    return &SymbolTable{store: make(map[string]Symbol)}
+}
+
+func NewEnclosedSymbolTable(outer *SymbolTable) *SymbolTable {
+    s := NewSymbolTable()
+    s.Outer = outer
+
+    return s
+}
+
+// Define binds name in the table's own scope. Redefining a name that is
+// already bound in this scope reuses its slot, the same way a second let in
+// the evaluator overwrites the binding in the enviroment.
+func (s *SymbolTable) Define(name string) Symbol {
+    scope := GlobalScope
+    if s.Outer != nil {
+        scope = LocalScope
+    }
+
+    if symbol, ok := s.store[name]; ok && symbol.Scope == scope {
+        return symbol
+    }
+
+    symbol := Symbol{Name: name, Scope: scope, Index: s.numDefinitions}
+    s.store[name] = symbol
+    s.numDefinitions++
+
+    return symbol
+}
+
+// DefineCell is Define for a local that closures capture and assign.
+func (s *SymbolTable) DefineCell(name string) Symbol {
+    symbol := s.Define(name)
+    symbol.Cell = symbol.Scope == LocalScope
+    s.store[name] = symbol
+
+    return symbol
+}
+
+func (s *SymbolTable) DefineBuiltin(index int, name string) Symbol {
+    symbol := Symbol{Name: name, Scope: BuiltinScope, Index: index}
+    s.store[name] = symbol
+
+    return symbol
+}
+
+func (s *SymbolTable) DefineFunctionName(name string) Symbol {
+    symbol := Symbol{Name: name, Scope: FunctionScope, Index: 0}
+    s.store[name] = symbol
+
+    return symbol
+}
+
+func (s *SymbolTable) defineFree(original Symbol) Symbol {
+    s.FreeSymbols = append(s.FreeSymbols, original)
+
+    symbol := Symbol{Name: original.Name, Scope: FreeScope, Index: len(s.FreeSymbols) - 1, Cell: original.Cell}
+    s.store[original.Name] = symbol
+
+    return symbol
+}
+
+func (s *SymbolTable) Resolve(name string) (Symbol, bool) {
+    symbol, ok := s.store[name]
+    if ok || s.Outer == nil {
+        return symbol, ok
+    }
+
+    symbol, ok = s.Outer.Resolve(name)
+    if !ok {
+        return symbol, ok
+    }
+
+    if symbol.Scope == GlobalScope || symbol.Scope == BuiltinScope {
+        return symbol, ok
+    }
+
+    return s.defineFree(symbol), true
+}
+
+// Global returns the outermost table, which holds the global bindings.
+func (s *SymbolTable) Global() *SymbolTable {
+    if s.Outer == nil {
+        return s
+    }
+
+    return s.Outer.Global()
+}
+
+// Names returns the names of the table's own definitions by slot index.
+func (s *SymbolTable) Names() []string {
+    names := make([]string, s.numDefinitions)
+    for _, symbol := range s.store {
+        if symbol.Scope == GlobalScope || symbol.Scope == LocalScope {
+            names[symbol.Index] = symbol.Name
+        }
+    }
+
+    return names
+}
@@ -0,0 +1,11 @@
+package evaluator
+
+import "interpreter/object"
+
+var builtins = map[string]*object.Builtin{}
+
+func init() {
+    for _, def := range object.Builtins {
+        builtins[def.Name] = def.Builtin
+    }
+}
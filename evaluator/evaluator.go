@@ -4,6 +4,10 @@ import (
 	"fmt"
 	"interpreter/ast"
 	"interpreter/object"
+	"io"
+	"math"
+	"math/big"
+	"strings"
 )
 
 var (
@@ -12,109 +16,293 @@ var (
     NULL = &object.Null{}
 )
 
+// maxIntegerBits bounds the size of integers created by ** and <<, so a
+// script can't exhaust the host's memory with a single expression.
+const maxIntegerBits = 1 << 24
+
+// flow tells how control leaves a node once it is evaluated.
+type flow int
+
+const (
+    flowNormal flow = iota
+    flowReturn
+    flowBreak
+    flowContinue
+    flowError
+)
+
+// result is the outcome of evaluating a node: its value and how control
+// leaves it. Any flow but flowNormal unwinds the enclosing nodes until one
+// handles it: loops handle break and continue, calls handle return, and
+// errors end the program.
+type result struct {
+    value object.Object
+    flow flow
+}
+
+// valueOf is the result of a node evaluating to obj, which is an error
+// result if obj is an error.
+func valueOf(obj object.Object) result {
+    if isError(obj) {
+        return result{value: obj, flow: flowError}
+    }
+
+    return result{value: obj}
+}
+
+func (r result) abrupt() bool {
+    return r.flow != flowNormal
+}
+
 func Eval(node ast.Node, env *object.Enviroment) object.Object {
+    return eval(node, env).value
+}
+
+func eval(node ast.Node, env *object.Enviroment) result {
     switch node := node.(type) {
         case *ast.Program:
             return evalProgram(node.Statements, env)
         case *ast.ExpressionStatement:
-            return Eval(node.Expression, env)
+            return eval(node.Expression, env)
         case *ast.PrefixExpression:
-            right := Eval(node.Right, env)
-            if isError(right) {
+            right := eval(node.Right, env)
+            if right.abrupt() {
                 return right
             }
-            return evalPrefixExpression(node.Operator, right)
+            return valueOf(evalPrefixExpression(node.Operator, right.value, env.Options().CheckOverflow))
         case *ast.IntegerLiteral:
-            return &object.Integer{Value: node.Value}
+            if node.Big != nil {
+                return valueOf(&object.BigInt{Value: node.Big})
+            }
+            return valueOf(&object.Integer{Value: node.Value})
+        case *ast.FloatLiteral:
+            return valueOf(&object.Float{Value: node.Value})
         case *ast.Boolean:
-            return nativeBoolToBooleanObject(node.Value)
+            return valueOf(nativeBoolToBooleanObject(node.Value))
         case *ast.InfixExpression:
-            left := Eval(node.Left, env)
-            if isError(left) {
+            if node.Operator == "&&" || node.Operator == "||" {
+                return evalLogicalExpression(node, env)
+            }
+
+            left := eval(node.Left, env)
+            if left.abrupt() {
                 return left
             }
-            right := Eval(node.Right, env)
-            if isError(right) {
+            right := eval(node.Right, env)
+            if right.abrupt() {
                 return right
             }
-            return evalInfixExpression(node.Operator, left, right)
+            return valueOf(evalInfixExpression(node.Operator, left.value, right.value, env.Options().CheckOverflow))
         case *ast.BlockStatement:
             return evalBlockStatement(node, env)
         case *ast.IfExpression:
             return evalIfExpression(node, env)
+        case *ast.WhileStatement:
+            return evalWhileStatement(node, env)
+        case *ast.ForStatement:
+            return evalForStatement(node, env)
+        case *ast.ForInStatement:
+            return evalForInStatement(node, env)
+        case *ast.BreakStatement:
+            return result{flow: flowBreak}
+        case *ast.ContinueStatement:
+            return result{flow: flowContinue}
         case *ast.ReturnStatement:
-            val := Eval(node.ReturnValue, env)
-            if isError(val) {
+            val := eval(node.ReturnValue, env)
+            if val.abrupt() {
                 return val
             }
-            return &object.RetrunValue{Value: val}
+            return result{value: val.value, flow: flowReturn}
         case *ast.LetStatemet:
-            val := Eval(node.Value, env)
-            if isError(val) {
+            val := eval(node.Value, env)
+            if val.abrupt() {
                 return val
             }
-            env.Set(node.Name.Value, val)
+            env.Set(node.Name.Value, val.value)
         case *ast.Indentifier:
-            return evalIdentifier(node, env)
+            return valueOf(evalIdentifier(node, env))
         case *ast.FunctionLiteral:
-            return &object.Function{Parameters: node.Parameters, Env: env, Body: node.Body}
+            return valueOf(&object.Function{
+                Parameters: node.Parameters,
+                Defaults: node.Defaults,
+                Rest: node.Rest,
+                Env: env,
+                Body: node.Body,
+                Name: node.Name,
+            })
         case *ast.CallExpression:
-            function := Eval(node.Function, env)
-            if isError(function) {
+            function := eval(node.Function, env)
+            if function.abrupt() {
                 return function
             }
-            args := evalExpressions(node.Arguments, env)
-
-            if len(args) == 1 && isError(args[0]) {
-                return args[0]
+            args, named, r := evalArguments(node.Arguments, env)
+            if r.abrupt() {
+                return r
             }
-            return applyFunction(function, args)
+            return valueOf(applyFunction(function.value, args, named, env.Options().Out()))
         case *ast.StringLiteral:
-            return &object.String{Value: node.Value}
+            return valueOf(&object.String{Value: node.Value})
+        case *ast.ArrayLiteral:
+            elements, r := evalExpressions(node.Elements, env)
+            if r.abrupt() {
+                return r
+            }
+            return valueOf(&object.Array{Elements: elements})
+        case *ast.AssignExpression:
+            return evalAssignExpression(node, env)
+        case *ast.IndexExpression:
+            left := eval(node.Left, env)
+            if left.abrupt() {
+                return left
+            }
+            index := eval(node.Index, env)
+            if index.abrupt() {
+                return index
+            }
+            return valueOf(evalIndexExpression(left.value, index.value))
+        case *ast.HashLiteral:
+            return evalHashLiteral(node, env)
     }
 
-    return nil
+    return result{}
 }
 
-func evalProgram(statements []ast.Statement, env *object.Enviroment) object.Object {
-    var result object.Object
+func evalProgram(statements []ast.Statement, env *object.Enviroment) result {
+    var r result
 
     for _, statement := range statements {
-        result = Eval(statement, env)
+        r = eval(statement, env)
 
-        switch result := result.(type) {
-            case *object.RetrunValue:
-                return result.Value
-            case *object.Error:
-                return result
+        switch r.flow {
+            case flowReturn:
+                return result{value: r.value}
+            case flowError:
+                return r
         }
     }
 
-    return result
+    return r
 }
 
-func evalIfExpression(ie *ast.IfExpression, env *object.Enviroment) object.Object {
-    condition := Eval(ie.Condition, env)
-    if isTruthy(condition) {
-        return Eval(ie.Consequence,env)
+func evalIfExpression(ie *ast.IfExpression, env *object.Enviroment) result {
+    condition := eval(ie.Condition, env)
+    if condition.abrupt() {
+        return condition
+    }
+
+    if isTruthy(condition.value) {
+        return eval(ie.Consequence,env)
     } else if ie.Alternative != nil {
-        return Eval(ie.Alternative, env)
+        return eval(ie.Alternative, env)
     } else {
-        return NULL
+        return valueOf(NULL)
+    }
+}
+
+func evalWhileStatement(ws *ast.WhileStatement, env *object.Enviroment) result {
+    for {
+        condition := eval(ws.Condition, env)
+        if condition.abrupt() {
+            return condition
+        }
+        if !isTruthy(condition.value) {
+            return valueOf(NULL)
+        }
+
+        if r, done := evalLoopBody(ws.Body, env); done {
+            return r
+        }
+    }
+}
+
+func evalForStatement(fs *ast.ForStatement, env *object.Enviroment) result {
+    if fs.Init != nil {
+        init := eval(fs.Init, env)
+        if init.abrupt() {
+            return init
+        }
+    }
+
+    for {
+        if fs.Condition != nil {
+            condition := eval(fs.Condition, env)
+            if condition.abrupt() {
+                return condition
+            }
+            if !isTruthy(condition.value) {
+                return valueOf(NULL)
+            }
+        }
+
+        if r, done := evalLoopBody(fs.Body, env); done {
+            return r
+        }
+
+        if fs.Step != nil {
+            step := eval(fs.Step, env)
+            if step.abrupt() {
+                return step
+            }
+        }
+    }
+}
+
+func evalForInStatement(fs *ast.ForInStatement, env *object.Enviroment) result {
+    iterable := eval(fs.Iterable, env)
+    if iterable.abrupt() {
+        return iterable
+    }
+
+    it, ok := iterable.value.(object.Iterable)
+    if !ok {
+        return valueOf(newError("not iterable: %s", object.TypeName(iterable.value)))
+    }
+    iterator := it.Iterator()
+
+    for {
+        key, value, ok := iterator.Next()
+        if !ok {
+            return valueOf(NULL)
+        }
+
+        if fs.Key != nil {
+            env.Set(fs.Key.Value, key)
+        } else if iterator.SingleKey {
+            value = key
+        }
+        env.Set(fs.Value.Value, value)
+
+        if r, done := evalLoopBody(fs.Body, env); done {
+            return r
+        }
     }
 }
 
+// evalLoopBody runs one iteration of a loop. It reports whether the loop
+// ends, and then the result of the loop statement: null after a break, or
+// the return or error that stopped it.
+func evalLoopBody(body *ast.BlockStatement, env *object.Enviroment) (result, bool) {
+    r := eval(body, env)
+
+    switch r.flow {
+        case flowBreak:
+            return valueOf(NULL), true
+        case flowReturn, flowError:
+            return r, true
+    }
+
+    return result{}, false
+}
+
+// isTruthy decides conditions and the operands of !, && and ||. Objects
+// implementing object.Truthable decide for themselves; null, false, zero,
+// "" and empty collections are false, anything else is true.
 func isTruthy(obj object.Object) bool {
-    switch obj {
-        case NULL:
-            return true
-        case TRUE:
-            return true
-        case FALSE:
-            return false
-        default:
-            return true
+    if t, ok := obj.(object.Truthable); ok {
+        return t.Truthy()
     }
+
+    return true
 }
 
 func nativeBoolToBooleanObject(input bool) *object.Boolean {
@@ -125,160 +313,838 @@ func nativeBoolToBooleanObject(input bool) *object.Boolean {
     return FALSE
 }
 
-func evalPrefixExpression(operator string, right object.Object) object.Object {
+func evalPrefixExpression(operator string, right object.Object, checked bool) object.Object {
     switch operator {
         case "!":
             return evalBangOperatorExpression(right)
         case "-":
-            return evalMinusPrefixOperatorExpression(right)
+            return evalMinusPrefixOperatorExpression(right, checked)
+        case "~":
+            return evalBitwiseNotOperatorExpression(right)
         default:
-            return newError("unknown operator: %s%s", operator, right.Type())
+            return newError("unknown operator: %s%s", operator, object.TypeName(right))
     }
 }
 
-func evalExpressions(exps []ast.Expression, env *object.Enviroment) []object.Object {
-    var result []object.Object
+func evalExpressions(exps []ast.Expression, env *object.Enviroment) ([]object.Object, result) {
+    var values []object.Object
 
     for _, e := range exps {
-        evaluated := Eval(e, env)
-        if isError(evaluated) {
-            return []object.Object{evaluated}
+        r := eval(e, env)
+        if r.abrupt() {
+            return nil, r
         }
-        result = append(result, evaluated)
+        values = append(values, r.value)
     }
 
-    return result
+    return values, result{}
 }
 
-func evalBlockStatement(block *ast.BlockStatement, env *object.Enviroment) object.Object {
-    var result object.Object
+func evalBlockStatement(block *ast.BlockStatement, env *object.Enviroment) result {
+    var r result
 
     for _, statement := range block.Statements {
-        result = Eval(statement, env)
-
-        if result != nil {
-            rt := result.Type()
-            if rt == object.RETURN_VALUE_OBJ || rt == object.ERRROR_OBJ {
-                return result
-            }
+        r = eval(statement, env)
+        if r.abrupt() {
+            return r
         }
     }
 
-    return result
+    if r.value == nil {
+        return valueOf(NULL)
+    }
+    return r
 }
 
 func evalBangOperatorExpression(right object.Object) object.Object {
-    switch right {
-        case TRUE:
-            return FALSE
-        case FALSE:
-            return TRUE
-        case NULL:
-            return TRUE
-        default:
-            return FALSE
-    }
+    return nativeBoolToBooleanObject(!isTruthy(right))
 }
 
-func evalMinusPrefixOperatorExpression(right object.Object) object.Object {
-   if right.Type() != object.INTEGER_OBJ {
-       return newError("unknown operator: -%s", right.Type())
+func evalMinusPrefixOperatorExpression(right object.Object, checked bool) object.Object {
+   switch right := right.(type) {
+       case *object.Integer:
+           if right.Value == math.MinInt64 {
+               if checked {
+                   return newError("integer overflow: -(%d)", right.Value)
+               }
+               return &object.BigInt{Value: new(big.Int).Neg(big.NewInt(right.Value))}
+           }
+           return &object.Integer{Value: -right.Value}
+       case *object.BigInt:
+           return object.IntegerFromBig(new(big.Int).Neg(right.Value))
+       case *object.Float:
+           return &object.Float{Value: -right.Value}
+       default:
+           return newError("unknown operator: -%s", object.TypeName(right))
    }
+}
 
-   value := right.(*object.Integer).Value
+// evalLogicalExpression evaluates && and ||. The right operand is only
+// evaluated when the left one does not decide the result, and the result is
+// the operand that decided it, so `name || "default"` works as expected.
+func evalLogicalExpression(node *ast.InfixExpression, env *object.Enviroment) result {
+    left := eval(node.Left, env)
+    if left.abrupt() {
+        return left
+    }
 
-   return &object.Integer{Value: -value}
+    if isTruthy(left.value) == (node.Operator == "||") {
+        return left
+    }
+
+    return eval(node.Right, env)
+}
+
+func evalBitwiseNotOperatorExpression(right object.Object) object.Object {
+    switch right := right.(type) {
+        case *object.Integer:
+            return &object.Integer{Value: ^right.Value}
+        case *object.BigInt:
+            return object.IntegerFromBig(new(big.Int).Not(right.Value))
+        default:
+            return newError("unknown operator: ~%s", object.TypeName(right))
+    }
 }
 
-func evalInfixExpression(operator string, left, right object.Object) object.Object {
+func evalInfixExpression(operator string, left, right object.Object, checked bool) object.Object {
     switch {
         case left.Type() == object.INTEGER_OBJ && right.Type() == object.INTEGER_OBJ:
-            return evalIntegerInfixExpression(operator, left, right)
+            return evalIntegerInfixExpression(operator, left, right, checked)
+        case isInteger(left) && isInteger(right):
+            return evalBigIntInfixExpression(operator, left, right)
+        case isNumber(left) && isNumber(right):
+            return evalFloatInfixExpression(operator, left, right)
         case operator == "==":
-            return nativeBoolToBooleanObject(left == right)
+            return nativeBoolToBooleanObject(objectsEqual(left, right))
         case operator == "!=":
-            return nativeBoolToBooleanObject(left != right)
+            return nativeBoolToBooleanObject(!objectsEqual(left, right))
         case left.Type() == object.STRING_OBJ && right.Type() == object.STRING_OBJ:
             return evalStringInfixExpression(operator, left, right)
         case left.Type() != right.Type():
-            return newError("type mismatch: %s %s %s", left.Type(), operator, right.Type())
+            return newError("type mismatch: %s %s %s", object.TypeName(left), operator, object.TypeName(right))
         default:
-            return newError("unknown operator: %s %s %s", left.Type(), operator, right.Type())
+            return newError("unknown operator: %s %s %s", object.TypeName(left), operator, object.TypeName(right))
     }
 }
 
-func evalIntegerInfixExpression(operator string, left, right object.Object) object.Object {
+func evalIntegerInfixExpression(operator string, left, right object.Object, checked bool) object.Object {
     leftVal := left.(*object.Integer).Value
     rightVal := right.(*object.Integer).Value
+    switch operator {
+        case "+", "-", "*", "/", "%", "**", "<<":
+            return evalIntegerArithmetic(operator, leftVal, rightVal, checked)
+        case "&":
+            return &object.Integer{Value: leftVal & rightVal}
+        case "|":
+            return &object.Integer{Value: leftVal | rightVal}
+        case "^":
+            return &object.Integer{Value: leftVal ^ rightVal}
+        case ">>":
+            if rightVal < 0 {
+                return newError("negative shift count: %d >> %d", leftVal, rightVal)
+            }
+            return &object.Integer{Value: leftVal >> rightVal}
+        case "<":
+            return nativeBoolToBooleanObject(leftVal < rightVal)
+        case ">":
+            return nativeBoolToBooleanObject(leftVal > rightVal)
+        case "<=":
+            return nativeBoolToBooleanObject(leftVal <= rightVal)
+        case ">=":
+            return nativeBoolToBooleanObject(leftVal >= rightVal)
+        case "==":
+            return nativeBoolToBooleanObject(leftVal == rightVal)
+        case "!=":
+            return nativeBoolToBooleanObject(leftVal != rightVal)
+        default:
+            return newError("unknown operator %s %s %s", object.TypeName(left), operator, object.TypeName(right))
+    }
+}
+
+// evalIntegerArithmetic applies an arithmetic operator to two integers. It
+// reports division by zero. Results that do not fit in an int64 become a
+// BigInt, or an error when checked.
+func evalIntegerArithmetic(operator string, leftVal, rightVal int64, checked bool) object.Object {
+    var result int64
+    overflow := false
+
     switch operator {
         case "+":
-            return &object.Integer{Value: leftVal + rightVal}
+            result = leftVal + rightVal
+            overflow = (rightVal > 0 && result < leftVal) || (rightVal < 0 && result > leftVal)
         case "-":
-            return &object.Integer{Value: leftVal - rightVal}
+            result = leftVal - rightVal
+            overflow = (rightVal < 0 && result < leftVal) || (rightVal > 0 && result > leftVal)
         case "*":
-            return &object.Integer{Value: leftVal * rightVal}
+            result = leftVal * rightVal
+            overflow = leftVal != 0 && (result/leftVal != rightVal || (leftVal == -1 && rightVal == math.MinInt64))
         case "/":
-            return &object.Integer{Value: leftVal / rightVal}
+            if rightVal == 0 {
+                return newError("division by zero: %d / %d", leftVal, rightVal)
+            }
+            result = leftVal / rightVal
+            overflow = leftVal == math.MinInt64 && rightVal == -1
+        case "%":
+            if rightVal == 0 {
+                return newError("division by zero: %d %% %d", leftVal, rightVal)
+            }
+            result = leftVal % rightVal
+        case "<<":
+            if rightVal < 0 {
+                return newError("negative shift count: %d << %d", leftVal, rightVal)
+            }
+            result = leftVal << rightVal
+            overflow = result>>rightVal != leftVal
+        case "**":
+            power := evalBigIntArithmetic(operator, big.NewInt(leftVal), big.NewInt(rightVal))
+            if _, ok := power.(*object.BigInt); ok && checked {
+                return newError("integer overflow: %d ** %d", leftVal, rightVal)
+            }
+            return power
+    }
+
+    if overflow {
+        if checked {
+            return newError("integer overflow: %d %s %d", leftVal, operator, rightVal)
+        }
+        return evalBigIntArithmetic(operator, big.NewInt(leftVal), big.NewInt(rightVal))
+    }
+
+    return &object.Integer{Value: result}
+}
+
+// evalBigIntInfixExpression evaluates an operation on two integers where at
+// least one is a BigInt.
+func evalBigIntInfixExpression(operator string, left, right object.Object) object.Object {
+    leftVal := toBigInt(left)
+    rightVal := toBigInt(right)
+    switch operator {
+        case "+", "-", "*", "/", "%", "**", "<<", ">>", "&", "|", "^":
+            return evalBigIntArithmetic(operator, leftVal, rightVal)
+        case "<":
+            return nativeBoolToBooleanObject(leftVal.Cmp(rightVal) < 0)
+        case ">":
+            return nativeBoolToBooleanObject(leftVal.Cmp(rightVal) > 0)
+        case "<=":
+            return nativeBoolToBooleanObject(leftVal.Cmp(rightVal) <= 0)
+        case ">=":
+            return nativeBoolToBooleanObject(leftVal.Cmp(rightVal) >= 0)
+        case "==":
+            return nativeBoolToBooleanObject(leftVal.Cmp(rightVal) == 0)
+        case "!=":
+            return nativeBoolToBooleanObject(leftVal.Cmp(rightVal) != 0)
+        default:
+            return newError("unknown operator: %s %s %s", object.TypeName(left), operator, object.TypeName(right))
+    }
+}
+
+// evalBigIntArithmetic is the arbitrary precision version of
+// evalIntegerArithmetic. Results that fit in an int64 are demoted again.
+func evalBigIntArithmetic(operator string, leftVal, rightVal *big.Int) object.Object {
+    result := new(big.Int)
+
+    switch operator {
+        case "+":
+            result.Add(leftVal, rightVal)
+        case "-":
+            result.Sub(leftVal, rightVal)
+        case "*":
+            result.Mul(leftVal, rightVal)
+        case "/", "%":
+            if rightVal.Sign() == 0 {
+                return newError("division by zero: %s %s %s", leftVal, operator, rightVal)
+            }
+            if operator == "/" {
+                result.Quo(leftVal, rightVal)
+            } else {
+                result.Rem(leftVal, rightVal)
+            }
+        case "**":
+            return evalBigIntPower(leftVal, rightVal)
+        case "<<", ">>":
+            if rightVal.Sign() < 0 {
+                return newError("negative shift count: %s %s %s", leftVal, operator, rightVal)
+            }
+            if operator == ">>" {
+                if !rightVal.IsInt64() || rightVal.Int64() > int64(leftVal.BitLen()) {
+                    // every bit is shifted out, leaving only the sign
+                    if leftVal.Sign() < 0 {
+                        return &object.Integer{Value: -1}
+                    }
+                    return &object.Integer{Value: 0}
+                }
+                result.Rsh(leftVal, uint(rightVal.Int64()))
+            } else {
+                if leftVal.Sign() != 0 && (!rightVal.IsInt64() || rightVal.Int64()+int64(leftVal.BitLen()) > maxIntegerBits) {
+                    return newError("integer too large: %s << %s", leftVal, rightVal)
+                }
+                result.Lsh(leftVal, uint(rightVal.Int64()))
+            }
+        case "&":
+            result.And(leftVal, rightVal)
+        case "|":
+            result.Or(leftVal, rightVal)
+        case "^":
+            result.Xor(leftVal, rightVal)
+    }
+
+    return object.IntegerFromBig(result)
+}
+
+// evalBigIntPower raises an integer to an integer power. A negative exponent
+// gives a float, like 2 ** -1 == 0.5.
+func evalBigIntPower(base, exponent *big.Int) object.Object {
+    if exponent.Sign() < 0 {
+        if base.Sign() == 0 {
+            return newError("division by zero: %s ** %s", base, exponent)
+        }
+        baseVal, _ := new(big.Float).SetInt(base).Float64()
+        exponentVal, _ := new(big.Float).SetInt(exponent).Float64()
+
+        return &object.Float{Value: math.Pow(baseVal, exponentVal)}
+    }
+
+    // 0, 1 and -1 stay small for any exponent, everything else grows by at
+    // least one bit per multiplication
+    if base.BitLen() > 1 && (!exponent.IsInt64() || exponent.Int64() > maxIntegerBits ||
+        int64(base.BitLen()-1)*exponent.Int64() > maxIntegerBits) {
+        return newError("integer too large: %s ** %s", base, exponent)
+    }
+
+    if !exponent.IsInt64() {
+        // base is 0, 1 or -1 here, so only the parity of the exponent matters
+        exponent = new(big.Int).And(exponent, big.NewInt(1))
+    }
+
+    return object.IntegerFromBig(new(big.Int).Exp(base, exponent, nil))
+}
+
+// evalFloatInfixExpression evaluates an operation on two numbers where at
+// least one is a float. The integer operand is promoted to a float.
+func evalFloatInfixExpression(operator string, left, right object.Object) object.Object {
+    leftVal := toFloat(left)
+    rightVal := toFloat(right)
+    switch operator {
+        case "+":
+            return &object.Float{Value: leftVal + rightVal}
+        case "-":
+            return &object.Float{Value: leftVal - rightVal}
+        case "*":
+            return &object.Float{Value: leftVal * rightVal}
+        case "/":
+            if rightVal == 0 {
+                return newError("division by zero: %s / %s", left.Inspect(), right.Inspect())
+            }
+            return &object.Float{Value: leftVal / rightVal}
+        case "%":
+            if rightVal == 0 {
+                return newError("division by zero: %s %% %s", left.Inspect(), right.Inspect())
+            }
+            return &object.Float{Value: math.Mod(leftVal, rightVal)}
+        case "**":
+            if leftVal == 0 && rightVal < 0 {
+                return newError("division by zero: %s ** %s", left.Inspect(), right.Inspect())
+            }
+            return &object.Float{Value: math.Pow(leftVal, rightVal)}
         case "<":
             return nativeBoolToBooleanObject(leftVal < rightVal)
         case ">":
             return nativeBoolToBooleanObject(leftVal > rightVal)
+        case "<=":
+            return nativeBoolToBooleanObject(leftVal <= rightVal)
+        case ">=":
+            return nativeBoolToBooleanObject(leftVal >= rightVal)
         case "==":
             return nativeBoolToBooleanObject(leftVal == rightVal)
         case "!=":
             return nativeBoolToBooleanObject(leftVal != rightVal)
         default:
-            return newError("unknown operator %s %s %s", left.Type(), operator, right.Type())
+            return newError("unknown operator: %s %s %s", object.TypeName(left), operator, object.TypeName(right))
     }
 }
 
-func evalStringInfixExpression(operator string, left, right object.Object) object.Object {
-    if operator != "+" {
-        return newError("unknown operator: %s %s %s", left.Type(), operator, right.Type())
+func isInteger(obj object.Object) bool {
+    return obj.Type() == object.INTEGER_OBJ || obj.Type() == object.BIGINT_OBJ
+}
+
+func isNumber(obj object.Object) bool {
+    return isInteger(obj) || obj.Type() == object.FLOAT_OBJ
+}
+
+func toBigInt(obj object.Object) *big.Int {
+    if integer, ok := obj.(*object.Integer); ok {
+        return big.NewInt(integer.Value)
+    }
+
+    return obj.(*object.BigInt).Value
+}
+
+func toFloat(obj object.Object) float64 {
+    switch obj := obj.(type) {
+        case *object.Integer:
+            return float64(obj.Value)
+        case *object.BigInt:
+            value, _ := new(big.Float).SetInt(obj.Value).Float64()
+            return value
+        default:
+            return obj.(*object.Float).Value
     }
-    
+}
+
+func evalStringInfixExpression(operator string, left, right object.Object) object.Object {
     leftVal := left.(*object.String).Value
     rightVal := right.(*object.String).Value
 
-    return &object.String{Value: leftVal + rightVal}
+    switch operator {
+        case "+":
+            return &object.String{Value: leftVal + rightVal}
+        case "<":
+            return nativeBoolToBooleanObject(leftVal < rightVal)
+        case ">":
+            return nativeBoolToBooleanObject(leftVal > rightVal)
+        case "<=":
+            return nativeBoolToBooleanObject(leftVal <= rightVal)
+        case ">=":
+            return nativeBoolToBooleanObject(leftVal >= rightVal)
+        default:
+            return newError("unknown operator: %s %s %s", object.TypeName(left), operator, object.TypeName(right))
+    }
+}
+
+// objectsEqual is the equality of == and !=. Numbers are equal when their
+// values are, whatever their types, and other objects of different types
+// never are. Strings compare by content, arrays and hashes by their
+// elements, and everything else by identity.
+func objectsEqual(left, right object.Object) bool {
+    return valuesEqual(left, right, map[[2]object.Object]bool{})
+}
+
+// valuesEqual compares left and right, taking the pairs of arrays and
+// hashes in seen as equal, so comparing collections that contain
+// themselves terminates.
+func valuesEqual(left, right object.Object, seen map[[2]object.Object]bool) bool {
+    if isNumber(left) && isNumber(right) {
+        return evalInfixExpression("==", left, right, false) == TRUE
+    }
+    if left == right {
+        return true
+    }
+    if left.Type() != right.Type() {
+        return false
+    }
+
+    pair := [2]object.Object{left, right}
+    if seen[pair] {
+        return true
+    }
+
+    switch left := left.(type) {
+        case *object.String:
+            return left.Value == right.(*object.String).Value
+        case *object.Array:
+            other := right.(*object.Array)
+            if len(left.Elements) != len(other.Elements) {
+                return false
+            }
+
+            seen[pair] = true
+            for i, el := range left.Elements {
+                if !valuesEqual(el, other.Elements[i], seen) {
+                    return false
+                }
+            }
+            return true
+        case *object.Hash:
+            other := right.(*object.Hash)
+            if len(left.Pairs) != len(other.Pairs) {
+                return false
+            }
+
+            seen[pair] = true
+            for key, p := range left.Pairs {
+                otherPair, ok := other.Pairs[key]
+                if !ok || !valuesEqual(p.Value, otherPair.Value, seen) {
+                    return false
+                }
+            }
+            return true
+        default:
+            return false
+    }
+}
+
+// evalAssignExpression assigns to a variable or an index expression and
+// returns the assigned value. A compound assignment like x += 1 applies its
+// operator to the current value first.
+func evalAssignExpression(node *ast.AssignExpression, env *object.Enviroment) result {
+    compound := node.Operator != "="
+
+    switch target := node.Target.(type) {
+        case *ast.Indentifier:
+            current, ok := env.Get(target.Value)
+            if !ok {
+                if _, ok := builtins[target.Value]; ok {
+                    return valueOf(newError("cannot assign to builtin %s", target.Value))
+                }
+                return valueOf(newError("identifier not found: %s", target.Value))
+            }
+            if !compound {
+                current = nil
+            }
+
+            value := evalAssignedValue(node, current, env)
+            if value.abrupt() {
+                return value
+            }
+
+            env.Assign(target.Value, value.value)
+            return value
+
+        case *ast.IndexExpression:
+            left := eval(target.Left, env)
+            if left.abrupt() {
+                return left
+            }
+            index := eval(target.Index, env)
+            if index.abrupt() {
+                return index
+            }
+
+            var current object.Object
+            if compound {
+                current = evalIndexExpression(left.value, index.value)
+                if isError(current) {
+                    return valueOf(current)
+                }
+            }
+
+            value := evalAssignedValue(node, current, env)
+            if value.abrupt() {
+                return value
+            }
+
+            return valueOf(evalSetIndexExpression(left.value, index.value, value.value))
+
+        default:
+            return valueOf(newError("cannot assign to %s", node.Target))
+    }
+}
+
+func evalAssignedValue(node *ast.AssignExpression, current object.Object, env *object.Enviroment) result {
+    value := eval(node.Value, env)
+    if value.abrupt() || current == nil {
+        return value
+    }
+
+    return valueOf(evalInfixExpression(strings.TrimSuffix(node.Operator, "="), current, value.value, env.Options().CheckOverflow))
+}
+
+func evalSetIndexExpression(left, index, value object.Object) object.Object {
+    switch {
+        case left.Type() == object.ARRAY_OBJ && index.Type() == object.INTEGER_OBJ:
+            elements := left.(*object.Array).Elements
+            idx := index.(*object.Integer).Value
+            if idx < 0 || idx >= int64(len(elements)) {
+                return newError("index out of range: %d", idx)
+            }
+            elements[idx] = value
+        case left.Type() == object.HASH_OBJ:
+            key, ok := index.(object.Hashable)
+            if !ok {
+                return newError("unusable as hash key: %s", object.TypeName(index))
+            }
+            left.(*object.Hash).Set(key, value)
+        default:
+            return newError("index assignment not supported: %s[%s]", object.TypeName(left), object.TypeName(index))
+    }
+
+    return value
 }
 
 func evalIdentifier(node *ast.Indentifier, env *object.Enviroment) object.Object {
-    val, ok := env.Get(node.Value)
+    if val, ok := env.Get(node.Value); ok {
+        return val
+    }
+
+    if builtin, ok := builtins[node.Value]; ok {
+        return builtin
+    }
+
+    return newError("identifier not found: %s", node.Value)
+}
+
+func evalIndexExpression(left, index object.Object) object.Object {
+    switch {
+        case left.Type() == object.ARRAY_OBJ && index.Type() == object.INTEGER_OBJ:
+            return evalArrayIndexExpression(left, index)
+        case left.Type() == object.HASH_OBJ:
+            return evalHashIndexExpression(left, index)
+        default:
+            return newError("index operator not supported: %s", object.TypeName(left))
+    }
+}
+
+func evalArrayIndexExpression(array, index object.Object) object.Object {
+    elements := array.(*object.Array).Elements
+    idx := index.(*object.Integer).Value
+    max := int64(len(elements) - 1)
+
+    if idx < 0 || idx > max {
+        return NULL
+    }
+
+    return elements[idx]
+}
+
+func evalHashIndexExpression(hash, index object.Object) object.Object {
+    key, ok := index.(object.Hashable)
+    if !ok {
+        return newError("unusable as hash key: %s", object.TypeName(index))
+    }
+
+    value, ok := hash.(*object.Hash).Get(key)
     if !ok {
-        return newError(fmt.Sprintf("identifier not found: %s", node.Value))
+        return NULL
+    }
+
+    return value
+}
+
+func evalHashLiteral(node *ast.HashLiteral, env *object.Enviroment) result {
+    hash := object.NewHash()
+
+    for i, keyNode := range node.Keys {
+        key := eval(keyNode, env)
+        if key.abrupt() {
+            return key
+        }
+
+        hashKey, ok := key.value.(object.Hashable)
+        if !ok {
+            return valueOf(newError("unusable as hash key: %s", object.TypeName(key.value)))
+        }
+
+        value := eval(node.Values[i], env)
+        if value.abrupt() {
+            return value
+        }
+
+        hash.Set(hashKey, value.value)
+    }
+
+    return valueOf(hash)
+}
+
+// NamedArgument is an argument passed by the name of its parameter.
+type NamedArgument struct {
+    Name string
+    Value object.Object
+}
+
+// evalArguments evaluates the arguments of a call. Spreads are expanded
+// into positional arguments; named arguments are returned separately. The
+// result is abrupt if evaluating an argument was.
+func evalArguments(exps []ast.Expression, env *object.Enviroment) ([]object.Object, []NamedArgument, result) {
+    args := []object.Object{}
+    var named []NamedArgument
+
+    for _, e := range exps {
+        switch e := e.(type) {
+            case *ast.SpreadExpression:
+                value := eval(e.Value, env)
+                if value.abrupt() {
+                    return nil, nil, value
+                }
+                values, err := spreadValues(value.value)
+                if err != nil {
+                    return nil, nil, valueOf(err)
+                }
+                args = append(args, values...)
+            case *ast.NamedArgument:
+                value := eval(e.Value, env)
+                if value.abrupt() {
+                    return nil, nil, value
+                }
+                named = append(named, NamedArgument{Name: e.Name.Value, Value: value.value})
+            default:
+                value := eval(e, env)
+                if value.abrupt() {
+                    return nil, nil, value
+                }
+                args = append(args, value.value)
+        }
     }
 
-    return val
+    return args, named, result{}
 }
 
-func applyFunction(fn object.Object, args []object.Object) object.Object {
-    function, ok := fn.(*object.Function)
+// spreadValues returns the elements of a spread argument, which are the
+// values a for-in loop with a single variable would bind.
+func spreadValues(obj object.Object) ([]object.Object, *object.Error) {
+    iterable, ok := obj.(object.Iterable)
     if !ok {
-        return newError("not a function %s", fn.Type())
+        return nil, newError("cannot spread %s", object.TypeName(obj))
     }
-    extentedEnv := extentedFunctionEnv(function, args)
-    evaluated := Eval(function.Body, extentedEnv)
 
-    return unwrapReturnValue(evaluated)
+    values := []object.Object{}
+    iterator := iterable.Iterator()
+    for {
+        key, value, ok := iterator.Next()
+        if !ok {
+            return values, nil
+        }
+        if iterator.SingleKey {
+            value = key
+        }
+        values = append(values, value)
+    }
 }
 
-func extentedFunctionEnv(fn *object.Function, args []object.Object) *object.Enviroment {
+// applyFunction calls fn. Builtins print to out.
+func applyFunction(fn object.Object, args []object.Object, named []NamedArgument, out io.Writer) object.Object {
+    switch fn := fn.(type) {
+        case *object.Function:
+            names := make([]string, len(fn.Parameters))
+            numDefaults := 0
+            for i, param := range fn.Parameters {
+                names[i] = param.Value
+                if i < len(fn.Defaults) && fn.Defaults[i] != nil {
+                    numDefaults++
+                }
+            }
+
+            values, err := bindArguments(fn.Name, names, numDefaults, fn.Rest != nil, args, named)
+            if err != nil {
+                return err
+            }
+
+            extentedEnv, errObj := extentedFunctionEnv(fn, values)
+            if errObj != nil {
+                return errObj
+            }
+            return unwrapReturnValue(eval(fn.Body, extentedEnv))
+        case *object.Builtin:
+            if len(named) > 0 {
+                return newError("builtin functions do not take named arguments")
+            }
+            if result := fn.Fn(out, args...); result != nil {
+                return result
+            }
+            return NULL
+        default:
+            return newError("not a function %s", object.TypeName(fn))
+    }
+}
+
+// bindArguments matches the arguments of a call to the parameters of the
+// function called name. The last numDefaults parameters have a default
+// value. It returns the value of each parameter, nil for those left to
+// their default, followed by an array of the remaining arguments if the
+// function has a rest parameter.
+func bindArguments(name string, params []string, numDefaults int, rest bool, args []object.Object, named []NamedArgument) ([]object.Object, *object.Error) {
+    required := len(params) - numDefaults
+    if (len(args) > len(params) && !rest) || (len(args) < required && len(named) == 0) {
+        return nil, arityError(name, required, len(params), rest, len(args))
+    }
+
+    values := make([]object.Object, len(params), len(params)+1)
+    copy(values, args)
+
+    for _, arg := range named {
+        index := -1
+        for i, param := range params {
+            if param == arg.Name {
+                index = i
+            }
+        }
+
+        if index < 0 {
+            return nil, newError("unknown argument %s to %s", arg.Name, functionName(name))
+        }
+        if values[index] != nil {
+            return nil, newError("duplicate argument %s to %s", arg.Name, functionName(name))
+        }
+        values[index] = arg.Value
+    }
+
+    for i := 0; i < required; i++ {
+        if values[i] == nil {
+            return nil, newError("missing argument %s to %s", params[i], functionName(name))
+        }
+    }
+
+    if rest {
+        extra := []object.Object{}
+        if len(args) > len(params) {
+            extra = append(extra, args[len(params):]...)
+        }
+        values = append(values, &object.Array{Elements: extra})
+    }
+
+    return values, nil
+}
+
+// arityError reports a call with the wrong number of arguments for a
+// function taking from min to max arguments, or more with a rest parameter.
+func arityError(name string, min, max int, rest bool, got int) *object.Error {
+    want := fmt.Sprintf("%d..%d", min, max)
+    switch {
+        case rest:
+            want = fmt.Sprintf("at least %d", min)
+        case min == max:
+            want = fmt.Sprintf("%d", min)
+    }
+
+    return newError("wrong number of arguments to %s: want=%s, got=%d", functionName(name), want, got)
+}
+
+func functionName(name string) string {
+    if name == "" {
+        return "anonymous function"
+    }
+
+    return "`" + name + "`"
+}
+
+// extentedFunctionEnv binds the parameters of fn to the values returned by
+// bindArguments, evaluating the defaults of those left without a value.
+func extentedFunctionEnv(fn *object.Function, values []object.Object) (*object.Enviroment, object.Object) {
     env := object.NewEnclosedEnviorment(fn.Env)
 
+    if fn.Rest != nil {
+        env.Set(fn.Rest.Value, values[len(fn.Parameters)])
+    }
     for paramIdx, param := range fn.Parameters {
-        env.Set(param.Value, args[paramIdx])
+        if values[paramIdx] != nil {
+            env.Set(param.Value, values[paramIdx])
+        }
     }
 
-    return env
+    for paramIdx, param := range fn.Parameters {
+        if values[paramIdx] == nil {
+            value := eval(fn.Defaults[paramIdx], env)
+            if value.flow == flowError {
+                return nil, value.value
+            }
+            if value.abrupt() {
+                return nil, newError("control flow leaves the default value of %s", param.Value)
+            }
+            env.Set(param.Value, value.value)
+        }
+    }
+
+    return env, nil
 }
 
-func unwrapReturnValue(obj object.Object) object.Object {
-    if returnValue, ok := obj.(*object.RetrunValue); ok {
-        return returnValue
+// unwrapReturnValue is the value of a call whose body evaluated to r. The
+// return that ended the body, if any, stops there and doesn't unwind the
+// caller.
+func unwrapReturnValue(r result) object.Object {
+    if r.value == nil {
+        return NULL
     }
 
-    return obj
+    return r.value
 }
 
 func newError(format string, a ...interface{}) *object.Error {
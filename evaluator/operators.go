@@ -0,0 +1,34 @@
+package evaluator
+
+import "interpreter/object"
+
+// The functions below expose the evaluator's operator semantics to the vm,
+// so both backends agree on the result, and the error, of every operation.
+
+func EvalPrefix(operator string, right object.Object, options object.Options) object.Object {
+    return evalPrefixExpression(operator, right, options.CheckOverflow)
+}
+
+func EvalInfix(operator string, left, right object.Object, options object.Options) object.Object {
+    return evalInfixExpression(operator, left, right, options.CheckOverflow)
+}
+
+func EvalIndex(left, index object.Object) object.Object {
+    return evalIndexExpression(left, index)
+}
+
+func EvalSetIndex(left, index, value object.Object) object.Object {
+    return evalSetIndexExpression(left, index, value)
+}
+
+func BindArguments(name string, params []string, numDefaults int, rest bool, args []object.Object, named []NamedArgument) ([]object.Object, *object.Error) {
+    return bindArguments(name, params, numDefaults, rest, args, named)
+}
+
+func Spread(obj object.Object) ([]object.Object, *object.Error) {
+    return spreadValues(obj)
+}
+
+func IsTruthy(obj object.Object) bool {
+    return isTruthy(obj)
+}
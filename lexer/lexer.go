@@ -1,32 +1,74 @@
 package lexer
 
 import (
+	"fmt"
 	"interpreter/token"
+	"strconv"
+	"strings"
+	"unicode"
+	"unicode/utf8"
 )
 
 type Lexer struct {
+    filename string
     input string
     position int
     readPosition int
-    ch byte
+    ch rune //current character, 0 at the end of the input
+    line int
+    column int //counted in characters, not bytes
+
+    comments []token.Token //comments skipped so far, in source order
 }
 
+// UNTERMINATED_COMMENT is the literal of the ILLEGAL token returned for a
+// block comment that is still open at the end of the input.
+const UNTERMINATED_COMMENT = "unterminated block comment"
+
+// UNTERMINATED_STRING is the literal of the ILLEGAL token returned for a
+// string literal that is still open at the end of the input.
+const UNTERMINATED_STRING = "unterminated string literal"
+
+// INVALID_UTF8 is the literal of the ILLEGAL token returned for bytes that
+// are not valid UTF-8.
+const INVALID_UTF8 = "invalid UTF-8 encoding"
+
 func New(input string) *Lexer {
-    l := &Lexer{input: input}
+    return NewFile("", input)
+}
+
+// NewFile creates a lexer whose token positions are reported relative to
+// the given filename.
+func NewFile(filename string, input string) *Lexer {
+    l := &Lexer{filename: filename, input: input, line: 1}
     l.readChar()
+    l.skipShebang()
 
     return l
 }
 
 func (l *Lexer) NextToken() token.Token {
-    var tok token.Token
+    pos, ok := l.skipWhitespace()
+    if !ok {
+        return token.Token{Type: token.ILLEGAL, Literal: UNTERMINATED_COMMENT, Pos: pos, End: l.currentPosition()}
+    }
+
+    pos = l.currentPosition()
+    tok := l.readToken()
+    tok.Pos = pos
+    tok.End = l.currentPosition()
+
+    return tok
+}
 
-    l.skipWhitespace()
+func (l *Lexer) readToken() token.Token {
+    var tok token.Token
 
     switch l.ch {
     case '"':
-        tok.Type = token.STRING
-        tok.Literal = l.readString()
+        tok = l.stringToken(l.readString())
+    case '`':
+        tok = l.stringToken(l.readRawString())
     case '=':
         if l.peekChar() == '=' {
             ch := l.ch
@@ -45,6 +87,8 @@ func (l *Lexer) NextToken() token.Token {
         }
     case ';':
         tok = newToken(token.SEMICOLON, l.ch)
+    case ':':
+        tok = newToken(token.COLON, l.ch)
     case '(':
         tok = newToken(token.LPAREN, l.ch)
     case ')':
@@ -52,33 +96,104 @@ func (l *Lexer) NextToken() token.Token {
     case ',':
         tok = newToken(token.COMMA, l.ch)
     case '+':
-        tok = newToken(token.PLUS, l.ch)
+        if l.peekChar() == '=' {
+            l.readChar()
+            tok = token.Token{Type: token.PLUS_ASSIGN, Literal: "+="}
+        } else {
+            tok = newToken(token.PLUS, l.ch)
+        }
     case '-':
-        tok = newToken(token.MINUS, l.ch)
+        if l.peekChar() == '=' {
+            l.readChar()
+            tok = token.Token{Type: token.MINUS_ASSIGN, Literal: "-="}
+        } else {
+            tok = newToken(token.MINUS, l.ch)
+        }
     case '/':
-        tok = newToken(token.SLASH, l.ch)
+        if l.peekChar() == '=' {
+            l.readChar()
+            tok = token.Token{Type: token.SLASH_ASSIGN, Literal: "/="}
+        } else {
+            tok = newToken(token.SLASH, l.ch)
+        }
     case '*':
-        tok = newToken(token.ASTERISK, l.ch)
+        if l.peekChar() == '*' {
+            l.readChar()
+            tok = token.Token{Type: token.POWER, Literal: "**"}
+        } else if l.peekChar() == '=' {
+            l.readChar()
+            tok = token.Token{Type: token.ASTERISK_ASSIGN, Literal: "*="}
+        } else {
+            tok = newToken(token.ASTERISK, l.ch)
+        }
+    case '%':
+        tok = newToken(token.PERCENT, l.ch)
+    case '^':
+        tok = newToken(token.BIT_XOR, l.ch)
+    case '~':
+        tok = newToken(token.BIT_NOT, l.ch)
     case '<':
-        tok = newToken(token.LT, l.ch)
+        if l.peekChar() == '=' {
+            l.readChar()
+            tok = token.Token{Type: token.LT_EQ, Literal: "<="}
+        } else if l.peekChar() == '<' {
+            l.readChar()
+            tok = token.Token{Type: token.SHL, Literal: "<<"}
+        } else {
+            tok = newToken(token.LT, l.ch)
+        }
     case '>':
-        tok = newToken(token.GT, l.ch)
+        if l.peekChar() == '=' {
+            l.readChar()
+            tok = token.Token{Type: token.GT_EQ, Literal: ">="}
+        } else if l.peekChar() == '>' {
+            l.readChar()
+            tok = token.Token{Type: token.SHR, Literal: ">>"}
+        } else {
+            tok = newToken(token.GT, l.ch)
+        }
+    case '&':
+        if l.peekChar() == '&' {
+            l.readChar()
+            tok = token.Token{Type: token.AND, Literal: "&&"}
+        } else {
+            tok = newToken(token.BIT_AND, l.ch)
+        }
+    case '|':
+        if l.peekChar() == '|' {
+            l.readChar()
+            tok = token.Token{Type: token.OR, Literal: "||"}
+        } else {
+            tok = newToken(token.BIT_OR, l.ch)
+        }
+    case '.':
+        if strings.HasPrefix(l.input[l.position:], "...") {
+            l.readChar()
+            l.readChar()
+            tok = token.Token{Type: token.ELLIPSIS, Literal: "..."}
+        } else {
+            tok = newToken(token.ILLEGAL, l.ch)
+        }
     case '{':
         tok = newToken(token.LBRACE, l.ch)
     case '}':
         tok = newToken(token.RBRACE, l.ch)
+    case '[':
+        tok = newToken(token.LBRACKET, l.ch)
+    case ']':
+        tok = newToken(token.RBRACKET, l.ch)
     case 0:
         tok.Literal = ""
         tok.Type = token.EOF
     default:
-        if isLetter(l.ch) {
+        if l.invalidEncoding() {
+            tok = token.Token{Type: token.ILLEGAL, Literal: INVALID_UTF8}
+        } else if isLetter(l.ch) {
             tok.Literal = l.readIdentifier()
             tok.Type = token.LookupIdent(tok.Literal)
             return tok
         } else if isDigit(l.ch) {
-            tok.Type = token.INT
-            tok.Literal = l.readNumber()
-            return tok
+            return l.readNumber()
         } else {
             tok = newToken(token.ILLEGAL, l.ch)
         }
@@ -89,66 +204,374 @@ func (l *Lexer) NextToken() token.Token {
 }
 
 func (l *Lexer) readChar() {
+    if l.readPosition > len(l.input) {
+        return
+    }
+
+    if l.ch == '\n' {
+        l.line += 1
+        l.column = 0
+    }
+    l.column += 1
+
+    width := 1
     if l.readPosition >= len(l.input) {
         l.ch = 0
     } else {
-        l.ch = l.input[l.readPosition]
+        l.ch, width = utf8.DecodeRuneInString(l.input[l.readPosition:])
     }
 
     l.position = l.readPosition
-    l.readPosition += 1
+    l.readPosition += width
 }
 
-func (l *Lexer) peekChar() byte {
+func (l *Lexer) peekChar() rune {
     if l.readPosition >= len(l.input) {
         return 0
     }
 
-    return l.input[l.readPosition]
+    r, _ := utf8.DecodeRuneInString(l.input[l.readPosition:])
+    return r
 }
 
-func (l *Lexer) skipWhitespace() {
-    for l.ch == ' ' || l.ch == '\t' || l.ch == '\n' || l.ch == '\r' {
+// invalidEncoding reports whether the current character is a byte that is
+// not valid UTF-8, as opposed to an encoded U+FFFD.
+func (l *Lexer) invalidEncoding() bool {
+    return l.ch == utf8.RuneError && l.readPosition-l.position == 1
+}
+
+func (l *Lexer) currentPosition() token.Position {
+    return token.Position{
+        Filename: l.filename,
+        Offset: l.position,
+        Line: l.line,
+        Column: l.column,
+    }
+}
+
+// skipShebang skips a "#!" interpreter line at the very start of the input,
+// so scripts can be made executable.
+func (l *Lexer) skipShebang() {
+    if l.ch != '#' || l.peekChar() != '!' {
+        return
+    }
+
+    for l.ch != '\n' && l.ch != 0 {
         l.readChar()
-    } }
+    }
+}
+
+// Comments returns the comments the lexer has skipped so far as COMMENT
+// tokens, so tools like a formatter can put them back.
+func (l *Lexer) Comments() []token.Token {
+    return l.comments
+}
+
+// skipWhitespace skips whitespace and comments. If a block comment is not
+// closed before the end of the input it returns the comment's position and
+// false.
+func (l *Lexer) skipWhitespace() (token.Position, bool) {
+    for {
+        switch {
+        case l.ch == ' ' || l.ch == '\t' || l.ch == '\n' || l.ch == '\r':
+            l.readChar()
+        case l.ch == '/' && l.peekChar() == '/':
+            l.readLineComment()
+        case l.ch == '/' && l.peekChar() == '*':
+            pos := l.currentPosition()
+            if !l.readBlockComment() {
+                return pos, false
+            }
+        default:
+            return token.Position{}, true
+        }
+    }
+}
+
+func (l *Lexer) readLineComment() {
+    pos := l.currentPosition()
+    for l.ch != '\n' && l.ch != 0 {
+        l.readChar()
+    }
+
+    l.addComment(pos)
+}
+
+// readBlockComment reads a /* */ comment. Block comments nest, so every /*
+// inside the comment needs its own */.
+func (l *Lexer) readBlockComment() bool {
+    pos := l.currentPosition()
+    depth := 0
+
+    for l.ch != 0 {
+        if l.ch == '/' && l.peekChar() == '*' {
+            depth++
+            l.readChar()
+        } else if l.ch == '*' && l.peekChar() == '/' {
+            depth--
+            l.readChar()
+        }
+        l.readChar()
+
+        if depth == 0 {
+            l.addComment(pos)
+            return true
+        }
+    }
+
+    return false
+}
+
+func (l *Lexer) addComment(pos token.Position) {
+    l.comments = append(l.comments, token.Token{
+        Type: token.COMMENT,
+        Literal: l.input[pos.Offset:l.position],
+        Pos: pos,
+        End: l.currentPosition(),
+    })
+}
 
 func (l *Lexer) readIdentifier() string {
     position := l.position
-    for isLetter(l.ch) {
+    for isLetter(l.ch) || unicode.IsDigit(l.ch) {
         l.readChar()
     }
 
     return l.input[position: l.position]
 }
 
-func (l* Lexer) readNumber() string {
+// readNumber reads an integer literal, or a float literal when the digits
+// are followed by a fraction like 1.5 or an exponent like 1e-3. Integers may
+// have a 0x, 0o or 0b base prefix, and underscores may separate digits.
+func (l* Lexer) readNumber() token.Token {
     position := l.position
-    for isDigit(l.ch) {
+    tokenType := token.TokenType(token.INT)
+
+    if l.ch == '0' && basePrefixes[l.peekChar()] != nil {
+        return l.readPrefixedInteger()
+    }
+
+    l.readDigits()
+
+    if l.ch == '.' && isDigit(l.peekChar()) {
+        tokenType = token.FLOAT
         l.readChar()
+        l.readDigits()
     }
 
-    return l.input[position: l.position]
+    if l.ch == 'e' || l.ch == 'E' {
+        tokenType = token.FLOAT
+        l.readChar()
+        if l.ch == '+' || l.ch == '-' {
+            l.readChar()
+        }
+
+        if !isDigit(l.ch) {
+            return token.Token{Type: token.ILLEGAL, Literal: "exponent has no digits in number literal"}
+        }
+        l.readDigits()
+    }
+
+    literal := l.input[position: l.position]
+    if !validUnderscores(literal, isDigit) {
+        return token.Token{Type: token.ILLEGAL, Literal: "'_' must separate successive digits in number literal"}
+    }
+
+    return token.Token{Type: tokenType, Literal: literal}
+}
+
+func (l *Lexer) readDigits() {
+    for isDigit(l.ch) || l.ch == '_' {
+        l.readChar()
+    }
+}
+
+type basePrefix struct {
+    name string
+    isDigit func(rune) bool
+}
+
+var basePrefixes = map[rune]*basePrefix{
+    'x': {"hexadecimal", isHexDigit},
+    'X': {"hexadecimal", isHexDigit},
+    'o': {"octal", func(ch rune) bool { return '0' <= ch && ch <= '7' }},
+    'O': {"octal", func(ch rune) bool { return '0' <= ch && ch <= '7' }},
+    'b': {"binary", func(ch rune) bool { return ch == '0' || ch == '1' }},
+    'B': {"binary", func(ch rune) bool { return ch == '0' || ch == '1' }},
+}
+
+// readPrefixedInteger reads a 0x, 0o or 0b integer. Every letter and digit
+// after the prefix is read as part of the literal, so 0b102 is reported as a
+// malformed literal instead of being split into two tokens.
+func (l *Lexer) readPrefixedInteger() token.Token {
+    position := l.position
+    prefix := basePrefixes[l.peekChar()]
+    l.readChar()
+    l.readChar()
+
+    digits := l.position
+    for isLetter(l.ch) || unicode.IsDigit(l.ch) {
+        l.readChar()
+    }
+    literal := l.input[position:l.position]
+
+    if strings.Trim(l.input[digits:l.position], "_") == "" {
+        return token.Token{Type: token.ILLEGAL, Literal: prefix.name + " literal has no digits"}
+    }
+
+    for _, ch := range l.input[digits:l.position] {
+        if ch != '_' && !prefix.isDigit(ch) {
+            return token.Token{Type: token.ILLEGAL, Literal: fmt.Sprintf("invalid digit %q in %s literal", ch, prefix.name)}
+        }
+    }
+
+    // an underscore may directly follow the base prefix, as in 0x_FF
+    if !validUnderscores("0"+l.input[digits:l.position], prefix.isDigit) {
+        return token.Token{Type: token.ILLEGAL, Literal: "'_' must separate successive digits in number literal"}
+    }
+
+    return token.Token{Type: token.INT, Literal: literal}
+}
+
+// validUnderscores reports whether every underscore in a number literal sits
+// between two digits.
+func validUnderscores(literal string, isDigit func(rune) bool) bool {
+    for i, ch := range literal {
+        if ch != '_' {
+            continue
+        }
+
+        if i == 0 || i == len(literal)-1 || !isDigit(rune(literal[i-1])) || !isDigit(rune(literal[i+1])) {
+            return false
+        }
+    }
+
+    return true
+}
+
+func (l *Lexer) stringToken(value string, errMsg string) token.Token {
+    if errMsg != "" {
+        return token.Token{Type: token.ILLEGAL, Literal: errMsg}
+    }
+
+    return token.Token{Type: token.STRING, Literal: value}
+}
+
+// readString reads a double quoted string and decodes its escape sequences.
+// It returns the decoded value, or a message describing the first invalid
+// escape. The rest of an invalid string is still consumed so lexing resumes
+// after its closing quote.
+func (l *Lexer) readString() (string, string) {
+    var out strings.Builder
+    errMsg := ""
+
+    for {
+        l.readChar()
+
+        switch l.ch {
+        case 0:
+            return "", UNTERMINATED_STRING
+        case '"':
+            return out.String(), errMsg
+        case utf8.RuneError:
+            if l.invalidEncoding() && errMsg == "" {
+                errMsg = INVALID_UTF8 + " in string literal"
+            }
+            out.WriteRune(l.ch)
+        case '\\':
+            l.readChar()
+            if msg := l.readEscape(&out); msg != "" && errMsg == "" {
+                errMsg = msg
+            }
+        default:
+            out.WriteRune(l.ch)
+        }
+    }
 }
 
-func (l *Lexer) readString() string {
+func (l *Lexer) readEscape(out *strings.Builder) string {
+    switch l.ch {
+    case 'n':
+        out.WriteByte('\n')
+    case 't':
+        out.WriteByte('\t')
+    case 'r':
+        out.WriteByte('\r')
+    case '"':
+        out.WriteByte('"')
+    case '\\':
+        out.WriteByte('\\')
+    case 'u':
+        return l.readUnicodeEscape(out)
+    case 0:
+        //the string is unterminated, which readString reports
+    default:
+        return fmt.Sprintf("invalid escape sequence \\%c in string literal", l.ch)
+    }
+
+    return ""
+}
+
+// readUnicodeEscape decodes a \u{...} escape holding 1 to 6 hex digits.
+func (l *Lexer) readUnicodeEscape(out *strings.Builder) string {
+    if l.peekChar() != '{' {
+        return "invalid unicode escape in string literal, expected \\u{...}"
+    }
+    l.readChar()
+
+    start := l.readPosition
+    for isHexDigit(l.peekChar()) {
+        l.readChar()
+    }
+    digits := l.input[start:l.readPosition]
+
+    if l.peekChar() != '}' || len(digits) == 0 || len(digits) > 6 {
+        return "invalid unicode escape in string literal, expected \\u{...}"
+    }
+    l.readChar()
+
+    value, _ := strconv.ParseUint(digits, 16, 32)
+    if !utf8.ValidRune(rune(value)) {
+        return fmt.Sprintf("invalid unicode code point \\u{%s} in string literal", digits)
+    }
+    out.WriteRune(rune(value))
+
+    return ""
+}
+
+// readRawString reads a backtick string. Raw strings may span lines and
+// have no escape sequences.
+func (l *Lexer) readRawString() (string, string) {
     position := l.position + 1
+    errMsg := ""
     for {
         l.readChar()
-        if l.ch == '"' || l.ch == 0 {
-            break
+        if l.ch == '`' {
+            return l.input[position:l.position], errMsg
+        }
+        if l.invalidEncoding() && errMsg == "" {
+            errMsg = INVALID_UTF8 + " in string literal"
+        }
+        if l.ch == 0 {
+            return "", UNTERMINATED_STRING
         }
     }
-    return l.input[position:l.position]
 }
 
-func isLetter(ch byte) bool {
-    return 'a' <= ch && ch <= 'z' || 'A' <= ch && ch <= 'Z' || ch == '_'
+func isLetter(ch rune) bool {
+    return unicode.IsLetter(ch) || ch == '_'
 }
 
-func isDigit(ch byte) bool {
+// isDigit only accepts ASCII digits, since they are the digits of number
+// literals. Identifiers may also contain other unicode digits.
+func isDigit(ch rune) bool {
     return '0' <= ch && ch <= '9'
 }
 
-func newToken(tokenType token.TokenType, ch byte) token.Token {
+func isHexDigit(ch rune) bool {
+    return isDigit(ch) || 'a' <= ch && ch <= 'f' || 'A' <= ch && ch <= 'F'
+}
+
+func newToken(tokenType token.TokenType, ch rune) token.Token {
     return token.Token{Type: tokenType, Literal: string(ch)}
 }
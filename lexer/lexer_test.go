@@ -12,7 +12,7 @@ func TestNextToken(t *testing.T) {
         x + y;
     };
     let result = add(five, ten);
-    !-/*5;
+    !-/ *5;
     5 < 10 > 5;
     if (5 < 10) {
         return true;
@@ -24,6 +24,8 @@ func TestNextToken(t *testing.T) {
     10 != 9;
     "foobar"
     "foo bar"
+    [1, 2];
+    {"foo": "bar"}
 `
 
     tests := []struct {
@@ -105,6 +107,17 @@ func TestNextToken(t *testing.T) {
         {token.SEMICOLON, ";"},
         {token.STRING, "foobar"},
         {token.STRING, "foo bar"},
+        {token.LBRACKET, "["},
+        {token.INT, "1"},
+        {token.COMMA, ","},
+        {token.INT, "2"},
+        {token.RBRACKET, "]"},
+        {token.SEMICOLON, ";"},
+        {token.LBRACE, "{"},
+        {token.STRING, "foo"},
+        {token.COLON, ":"},
+        {token.STRING, "bar"},
+        {token.RBRACE, "}"},
         {token.EOF, ""},
     }
 
@@ -120,3 +133,478 @@ func TestNextToken(t *testing.T) {
         }
     }
 }
+
+func TestTokenPositions(t *testing.T) {
+    input := `let x = 5;
+  "ab" == x`
+
+    tests := []struct {
+        expectedType token.TokenType
+        expectedOffset int
+        expectedLine int
+        expectedColumn int
+        expectedEndOffset int
+    }{
+        {token.LET, 0, 1, 1, 3},
+        {token.IDENT, 4, 1, 5, 5},
+        {token.ASSIGN, 6, 1, 7, 7},
+        {token.INT, 8, 1, 9, 9},
+        {token.SEMICOLON, 9, 1, 10, 10},
+        {token.STRING, 13, 2, 3, 17},
+        {token.EQ, 18, 2, 8, 20},
+        {token.IDENT, 21, 2, 11, 22},
+        {token.EOF, 22, 2, 12, 22},
+    }
+
+    l := NewFile("test.mk", input)
+    for i, tt := range tests {
+        tok := l.NextToken()
+        if tok.Type != tt.expectedType {
+            t.Fatalf("tests[%d], expected %q, got %q", i, tt.expectedType, tok.Type)
+        }
+
+        if tok.Pos.Filename != "test.mk" {
+            t.Errorf("tests[%d] expected filename %q, got %q", i, "test.mk", tok.Pos.Filename)
+        }
+
+        if tok.Pos.Offset != tt.expectedOffset || tok.Pos.Line != tt.expectedLine || tok.Pos.Column != tt.expectedColumn {
+            t.Errorf(
+                "tests[%d] expected pos %d:%d (offset %d), got %d:%d (offset %d)",
+                i, tt.expectedLine, tt.expectedColumn, tt.expectedOffset,
+                tok.Pos.Line, tok.Pos.Column, tok.Pos.Offset,
+            )
+        }
+
+        if tok.End.Offset != tt.expectedEndOffset {
+            t.Errorf("tests[%d] expected end offset %d, got %d", i, tt.expectedEndOffset, tok.End.Offset)
+        }
+    }
+}
+
+func TestShebangLine(t *testing.T) {
+    input := "#!/usr/bin/env interpreter run\nlet x = 1;"
+
+    l := New(input)
+    tok := l.NextToken()
+    if tok.Type != token.LET {
+        t.Fatalf("expected %q, got %q", token.LET, tok.Type)
+    }
+
+    if tok.Pos.Line != 2 || tok.Pos.Column != 1 {
+        t.Errorf("expected position 2:1, got %s", tok.Pos)
+    }
+}
+
+func TestComments(t *testing.T) {
+    input := `// leading comment
+let x = 10 / 2; // trailing comment
+/* block
+   /* nested */ still a comment */
+x /**/ * 2;
+`
+
+    tests := []struct {
+        expectedType token.TokenType
+        expectedLiteral string
+    }{
+        {token.LET, "let"},
+        {token.IDENT, "x"},
+        {token.ASSIGN, "="},
+        {token.INT, "10"},
+        {token.SLASH, "/"},
+        {token.INT, "2"},
+        {token.SEMICOLON, ";"},
+        {token.IDENT, "x"},
+        {token.ASTERISK, "*"},
+        {token.INT, "2"},
+        {token.SEMICOLON, ";"},
+        {token.EOF, ""},
+    }
+
+    l := New(input)
+
+    for i, tt := range tests {
+        tok := l.NextToken()
+
+        if tok.Type != tt.expectedType || tok.Literal != tt.expectedLiteral {
+            t.Fatalf("tests[%d] - expected %q %q, got %q %q", i, tt.expectedType, tt.expectedLiteral, tok.Type, tok.Literal)
+        }
+    }
+
+    expectedComments := []string{
+        "// leading comment",
+        "// trailing comment",
+        "/* block\n   /* nested */ still a comment */",
+        "/**/",
+    }
+
+    comments := l.Comments()
+    if len(comments) != len(expectedComments) {
+        t.Fatalf("expected %d comments, got %d", len(expectedComments), len(comments))
+    }
+
+    for i, expected := range expectedComments {
+        if comments[i].Type != token.COMMENT || comments[i].Literal != expected {
+            t.Errorf("comments[%d] - expected %q, got %q %q", i, expected, comments[i].Type, comments[i].Literal)
+        }
+    }
+
+    if comments[2].Pos.Line != 3 || comments[2].End.Line != 4 {
+        t.Errorf("wrong block comment position %s - %s", comments[2].Pos, comments[2].End)
+    }
+}
+
+func TestUnterminatedBlockComment(t *testing.T) {
+    input := "let x = 1;\n/* outer /* inner */ still open"
+
+    l := New(input)
+    for i := 0; i < 5; i++ {
+        l.NextToken()
+    }
+
+    tok := l.NextToken()
+    if tok.Type != token.ILLEGAL || tok.Literal != UNTERMINATED_COMMENT {
+        t.Fatalf("expected unterminated comment error, got %q %q", tok.Type, tok.Literal)
+    }
+
+    if tok.Pos.Line != 2 || tok.Pos.Column != 1 {
+        t.Errorf("expected position 2:1, got %s", tok.Pos)
+    }
+
+    if tok = l.NextToken(); tok.Type != token.EOF {
+        t.Errorf("expected EOF after error, got %q", tok.Type)
+    }
+}
+
+func TestStringEscapes(t *testing.T) {
+    tests := []struct {
+        input string
+        expected string
+    }{
+        {`"line\nnext"`, "line\nnext"},
+        {`"a\tb\r"`, "a\tb\r"},
+        {`"say \"hi\""`, `say "hi"`},
+        {`"back\\slash"`, `back\slash`},
+        {`"\u{41}\u{e9}\u{1F600}"`, "Aé😀"},
+        {`"héllo"`, "héllo"},
+        {"`raw \\n \"string\"`", `raw \n "string"`},
+        {"`multi\nline`", "multi\nline"},
+    }
+
+    for _, tt := range tests {
+        tok := New(tt.input).NextToken()
+
+        if tok.Type != token.STRING || tok.Literal != tt.expected {
+            t.Errorf("%s: expected STRING %q, got %q %q", tt.input, tt.expected, tok.Type, tok.Literal)
+        }
+    }
+}
+
+func TestStringErrors(t *testing.T) {
+    tests := []struct {
+        input string
+        expected string
+    }{
+        {`"unterminated`, UNTERMINATED_STRING},
+        {`"ends in escape\`, UNTERMINATED_STRING},
+        {"`unterminated raw", UNTERMINATED_STRING},
+        {`"bad \q escape"`, `invalid escape sequence \q in string literal`},
+        {`"\u41"`, `invalid unicode escape in string literal, expected \u{...}`},
+        {`"\u{}"`, `invalid unicode escape in string literal, expected \u{...}`},
+        {`"\u{1234567}"`, `invalid unicode escape in string literal, expected \u{...}`},
+        {`"\u{D800}"`, `invalid unicode code point \u{D800} in string literal`},
+        {`"\u{110000}"`, `invalid unicode code point \u{110000} in string literal`},
+    }
+
+    for _, tt := range tests {
+        l := New(tt.input + ";")
+        tok := l.NextToken()
+
+        if tok.Type != token.ILLEGAL || tok.Literal != tt.expected {
+            t.Errorf("%s: expected ILLEGAL %q, got %q %q", tt.input, tt.expected, tok.Type, tok.Literal)
+        }
+
+        if tok.Pos.Column != 1 {
+            t.Errorf("%s: expected error at column 1, got %s", tt.input, tok.Pos)
+        }
+    }
+
+    // lexing resumes after the closing quote of an invalid string
+    l := New(`"bad \q"; 5`)
+    l.NextToken()
+    if tok := l.NextToken(); tok.Type != token.SEMICOLON {
+        t.Errorf("expected SEMICOLON after invalid string, got %q", tok.Type)
+    }
+}
+
+func TestUnicodeIdentifiers(t *testing.T) {
+    input := "let größe = 1;\nlet 名前2 = \"ü\" + größe;"
+
+    tests := []struct {
+        expectedType token.TokenType
+        expectedLiteral string
+        line int
+        column int
+    }{
+        {token.LET, "let", 1, 1},
+        {token.IDENT, "größe", 1, 5},
+        {token.ASSIGN, "=", 1, 11},
+        {token.INT, "1", 1, 13},
+        {token.SEMICOLON, ";", 1, 14},
+        {token.LET, "let", 2, 1},
+        {token.IDENT, "名前2", 2, 5},
+        {token.ASSIGN, "=", 2, 9},
+        {token.STRING, "ü", 2, 11},
+        {token.PLUS, "+", 2, 15},
+        {token.IDENT, "größe", 2, 17},
+        {token.SEMICOLON, ";", 2, 22},
+        {token.EOF, "", 2, 23},
+    }
+
+    l := New(input)
+
+    for i, tt := range tests {
+        tok := l.NextToken()
+
+        if tok.Type != tt.expectedType || tok.Literal != tt.expectedLiteral {
+            t.Fatalf("tests[%d] - expected %q %q, got %q %q", i, tt.expectedType, tt.expectedLiteral, tok.Type, tok.Literal)
+        }
+
+        if tok.Pos.Line != tt.line || tok.Pos.Column != tt.column {
+            t.Errorf("tests[%d] - expected position %d:%d, got %s", i, tt.line, tt.column, tok.Pos)
+        }
+    }
+}
+
+func TestInvalidUTF8(t *testing.T) {
+    tests := []struct {
+        input string
+        expected string
+    }{
+        {"\xff", INVALID_UTF8},
+        {"\"a\xffb\"", INVALID_UTF8 + " in string literal"},
+        {"`a\xffb`", INVALID_UTF8 + " in string literal"},
+        {"€", "€"},
+    }
+
+    for _, tt := range tests {
+        tok := New(tt.input).NextToken()
+
+        if tok.Type != token.ILLEGAL || tok.Literal != tt.expected {
+            t.Errorf("%q: expected ILLEGAL %q, got %q %q", tt.input, tt.expected, tok.Type, tok.Literal)
+        }
+    }
+}
+
+func TestNumberLiterals(t *testing.T) {
+    tests := []struct {
+        input string
+        expectedType token.TokenType
+        expectedLiteral string
+    }{
+        {"42", token.INT, "42"},
+        {"1.5", token.FLOAT, "1.5"},
+        {"0.25", token.FLOAT, "0.25"},
+        {"1e3", token.FLOAT, "1e3"},
+        {"1e-3", token.FLOAT, "1e-3"},
+        {"2.5E+10", token.FLOAT, "2.5E+10"},
+        {"1e", token.ILLEGAL, "exponent has no digits in number literal"},
+        {"1e+", token.ILLEGAL, "exponent has no digits in number literal"},
+        {"0xFF", token.INT, "0xFF"},
+        {"0o755", token.INT, "0o755"},
+        {"0B1010", token.INT, "0B1010"},
+        {"1_000_000", token.INT, "1_000_000"},
+        {"0x_dead_beef", token.INT, "0x_dead_beef"},
+        {"1_000.000_1", token.FLOAT, "1_000.000_1"},
+        {"0x", token.ILLEGAL, "hexadecimal literal has no digits"},
+        {"0o_", token.ILLEGAL, "octal literal has no digits"},
+        {"0b102", token.ILLEGAL, "invalid digit '2' in binary literal"},
+        {"0o78", token.ILLEGAL, "invalid digit '8' in octal literal"},
+        {"0xfg", token.ILLEGAL, "invalid digit 'g' in hexadecimal literal"},
+        {"1__000", token.ILLEGAL, "'_' must separate successive digits in number literal"},
+        {"1000_", token.ILLEGAL, "'_' must separate successive digits in number literal"},
+        {"1_.5", token.ILLEGAL, "'_' must separate successive digits in number literal"},
+        {"0x_1_", token.ILLEGAL, "'_' must separate successive digits in number literal"},
+    }
+
+    for _, tt := range tests {
+        tok := New(tt.input).NextToken()
+
+        if tok.Type != tt.expectedType || tok.Literal != tt.expectedLiteral {
+            t.Errorf("%s: expected %q %q, got %q %q", tt.input, tt.expectedType, tt.expectedLiteral, tok.Type, tok.Literal)
+        }
+    }
+
+    // a dot that is not followed by a digit is not part of the number
+    l := New("1.")
+    if tok := l.NextToken(); tok.Type != token.INT || tok.Literal != "1" {
+        t.Errorf("expected INT 1, got %q %q", tok.Type, tok.Literal)
+    }
+}
+
+func TestComparisonAndLogicalOperators(t *testing.T) {
+    input := "a <= b >= c && d || e < f > g & |"
+
+    tests := []struct {
+        expectedType token.TokenType
+        expectedLiteral string
+    }{
+        {token.IDENT, "a"},
+        {token.LT_EQ, "<="},
+        {token.IDENT, "b"},
+        {token.GT_EQ, ">="},
+        {token.IDENT, "c"},
+        {token.AND, "&&"},
+        {token.IDENT, "d"},
+        {token.OR, "||"},
+        {token.IDENT, "e"},
+        {token.LT, "<"},
+        {token.IDENT, "f"},
+        {token.GT, ">"},
+        {token.IDENT, "g"},
+        {token.BIT_AND, "&"},
+        {token.BIT_OR, "|"},
+        {token.EOF, ""},
+    }
+
+    l := New(input)
+
+    for i, tt := range tests {
+        tok := l.NextToken()
+
+        if tok.Type != tt.expectedType || tok.Literal != tt.expectedLiteral {
+            t.Fatalf("tests[%d] - expected %q %q, got %q %q", i, tt.expectedType, tt.expectedLiteral, tok.Type, tok.Literal)
+        }
+    }
+}
+
+func TestArithmeticAndBitwiseOperators(t *testing.T) {
+    input := "a % b ** c * d & e | f ^ ~g << h >> i"
+
+    tests := []struct {
+        expectedType token.TokenType
+        expectedLiteral string
+    }{
+        {token.IDENT, "a"},
+        {token.PERCENT, "%"},
+        {token.IDENT, "b"},
+        {token.POWER, "**"},
+        {token.IDENT, "c"},
+        {token.ASTERISK, "*"},
+        {token.IDENT, "d"},
+        {token.BIT_AND, "&"},
+        {token.IDENT, "e"},
+        {token.BIT_OR, "|"},
+        {token.IDENT, "f"},
+        {token.BIT_XOR, "^"},
+        {token.BIT_NOT, "~"},
+        {token.IDENT, "g"},
+        {token.SHL, "<<"},
+        {token.IDENT, "h"},
+        {token.SHR, ">>"},
+        {token.IDENT, "i"},
+        {token.EOF, ""},
+    }
+
+    l := New(input)
+
+    for i, tt := range tests {
+        tok := l.NextToken()
+
+        if tok.Type != tt.expectedType || tok.Literal != tt.expectedLiteral {
+            t.Fatalf("tests[%d] - expected %q %q, got %q %q", i, tt.expectedType, tt.expectedLiteral, tok.Type, tok.Literal)
+        }
+    }
+}
+
+func TestAssignmentOperators(t *testing.T) {
+    input := "a = b += c -= d *= e /= f ** g == h"
+
+    tests := []struct {
+        expectedType token.TokenType
+        expectedLiteral string
+    }{
+        {token.IDENT, "a"},
+        {token.ASSIGN, "="},
+        {token.IDENT, "b"},
+        {token.PLUS_ASSIGN, "+="},
+        {token.IDENT, "c"},
+        {token.MINUS_ASSIGN, "-="},
+        {token.IDENT, "d"},
+        {token.ASTERISK_ASSIGN, "*="},
+        {token.IDENT, "e"},
+        {token.SLASH_ASSIGN, "/="},
+        {token.IDENT, "f"},
+        {token.POWER, "**"},
+        {token.IDENT, "g"},
+        {token.EQ, "=="},
+        {token.IDENT, "h"},
+        {token.EOF, ""},
+    }
+
+    l := New(input)
+
+    for i, tt := range tests {
+        tok := l.NextToken()
+
+        if tok.Type != tt.expectedType || tok.Literal != tt.expectedLiteral {
+            t.Fatalf("tests[%d] - expected %q %q, got %q %q", i, tt.expectedType, tt.expectedLiteral, tok.Type, tok.Literal)
+        }
+    }
+}
+
+func TestLoopKeywords(t *testing.T) {
+    input := "while for break continue in whilst"
+
+    tests := []struct {
+        expectedType token.TokenType
+        expectedLiteral string
+    }{
+        {token.WHILE, "while"},
+        {token.FOR, "for"},
+        {token.BREAK, "break"},
+        {token.CONTINUE, "continue"},
+        {token.IN, "in"},
+        {token.IDENT, "whilst"},
+        {token.EOF, ""},
+    }
+
+    l := New(input)
+
+    for i, tt := range tests {
+        tok := l.NextToken()
+
+        if tok.Type != tt.expectedType || tok.Literal != tt.expectedLiteral {
+            t.Fatalf("tests[%d] - expected %q %q, got %q %q", i, tt.expectedType, tt.expectedLiteral, tok.Type, tok.Literal)
+        }
+    }
+}
+
+func TestEllipsis(t *testing.T) {
+    input := "f(...xs) . .."
+
+    tests := []struct {
+        expectedType token.TokenType
+        expectedLiteral string
+    }{
+        {token.IDENT, "f"},
+        {token.LPAREN, "("},
+        {token.ELLIPSIS, "..."},
+        {token.IDENT, "xs"},
+        {token.RPAREN, ")"},
+        {token.ILLEGAL, "."},
+        {token.ILLEGAL, "."},
+        {token.ILLEGAL, "."},
+        {token.EOF, ""},
+    }
+
+    l := New(input)
+
+    for i, tt := range tests {
+        tok := l.NextToken()
+
+        if tok.Type != tt.expectedType || tok.Literal != tt.expectedLiteral {
+            t.Fatalf("tests[%d] - expected %q %q, got %q %q", i, tt.expectedType, tt.expectedLiteral, tok.Type, tok.Literal)
+        }
+    }
+}
@@ -1,10 +1,164 @@
 package main
 
 import (
+	"errors"
+	"flag"
+	"fmt"
+	"interpreter/ast"
+	"interpreter/compiler"
+	"interpreter/evaluator"
+	"interpreter/lexer"
+	"interpreter/object"
+	"interpreter/parser"
 	"interpreter/repl"
+	"interpreter/vm"
+	"io"
 	"os"
 )
 
+const usage = `usage:
+    interpreter                 start the REPL, or run a program piped to stdin
+    interpreter run <file>      run a script file ("-" reads stdin)
+    interpreter <file>          same as run
+    interpreter -e <source>     evaluate source and print the result
+
+flags:
+    -vm                         compile to bytecode and run on the virtual machine
+    -checked                    report integer overflow as an error instead of promoting to a big integer
+`
+
 func main () {
-    repl.Start(os.Stdin, os.Stdout)
+    os.Exit(run(os.Args[1:], os.Stdin, os.Stdout, os.Stderr))
+}
+
+func run(args []string, stdin *os.File, stdout, stderr io.Writer) int {
+    flags := flag.NewFlagSet("interpreter", flag.ContinueOnError)
+    flags.SetOutput(stderr)
+    flags.Usage = func() { io.WriteString(stderr, usage) }
+    expr := flags.String("e", "", "evaluate `source` and print the result")
+    useVm := flags.Bool("vm", false, "run programs on the bytecode virtual machine")
+    checked := flags.Bool("checked", false, "report integer overflow as an error instead of promoting to a big integer")
+
+    if err := flags.Parse(args); err != nil {
+        return 2
+    }
+    args = flags.Args()
+
+    if *useVm {
+        evaluate = evaluateVm
+    }
+    options := object.Options{Output: stdout, CheckOverflow: *checked}
+
+    if *expr != "" {
+        if len(args) != 0 {
+            flags.Usage()
+            return 2
+        }
+        return execute("<expr>", *expr, options, stderr, true)
+    }
+
+    if len(args) > 0 && args[0] == "run" {
+        args = args[1:]
+        if len(args) == 0 {
+            flags.Usage()
+            return 2
+        }
+    }
+
+    switch len(args) {
+    case 0:
+        if isTerminal(stdin) {
+            repl.Start(stdin, options)
+            return 0
+        }
+        return executeFile("-", stdin, options, stderr)
+    case 1:
+        return executeFile(args[0], stdin, options, stderr)
+    default:
+        flags.Usage()
+        return 2
+    }
+}
+
+func executeFile(filename string, stdin io.Reader, options object.Options, stderr io.Writer) int {
+    var src []byte
+    var err error
+
+    if filename == "-" {
+        filename = "<stdin>"
+        src, err = io.ReadAll(stdin)
+    } else {
+        src, err = os.ReadFile(filename)
+    }
+
+    if err != nil {
+        fmt.Fprintf(stderr, "interpreter: %s\n", err)
+        return 2
+    }
+
+    return execute(filename, string(src), options, stderr, false)
+}
+
+// execute parses and evaluates src, reporting errors on stderr. It returns
+// the process exit status: 0 on success and 1 on a parse or runtime error.
+func execute(filename, src string, options object.Options, stderr io.Writer, printResult bool) int {
+    l := lexer.NewFile(filename, src)
+    p := parser.New(l)
+    program := p.ParseProgram()
+
+    if len(p.Errors()) != 0 {
+        for _, err := range p.Errors() {
+            fmt.Fprintln(stderr, err)
+        }
+        return 1
+    }
+
+    evaluated, err := evaluate(program, options)
+    if err != nil {
+        fmt.Fprintf(stderr, "%s: runtime error: %s\n", filename, err)
+        return 1
+    }
+
+    if printResult && evaluated != nil && evaluated != evaluator.NULL {
+        fmt.Fprintln(options.Out(), evaluated.Inspect())
+    }
+
+    return 0
+}
+
+// evaluate runs a parsed program on the selected backend. The tree walking
+// evaluator is the default, -vm switches to the bytecode virtual machine.
+var evaluate = evaluateTree
+
+func evaluateTree(program *ast.Program, options object.Options) (object.Object, error) {
+    evaluated := evaluator.Eval(program, object.NewEnviromentWithOptions(options))
+
+    if errObj, ok := evaluated.(*object.Error); ok {
+        return nil, errors.New(errObj.Message)
+    }
+
+    return evaluated, nil
+}
+
+func evaluateVm(program *ast.Program, options object.Options) (object.Object, error) {
+    comp := compiler.New()
+    if err := comp.Compile(program); err != nil {
+        return nil, err
+    }
+
+    machine := vm.NewWithOptions(comp.Bytecode(), options)
+    if err := machine.Run(); err != nil {
+        return nil, err
+    }
+
+    return machine.LastPoppedStackElem(), nil
+}
+
+func isTerminal(f *os.File) bool {
+    fi, err := f.Stat()
+    if err != nil {
+        return false
+    }
+
+    return fi.Mode()&os.ModeCharDevice != 0
 }
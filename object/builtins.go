@@ -0,0 +1,339 @@
+package object
+
+import (
+	"fmt"
+	"io"
+	"math"
+	"math/big"
+	"strconv"
+	"strings"
+	"unicode/utf8"
+)
+
+// Builtins lists every native function available to scripts. The order is
+// stable so backends can refer to a builtin by its index.
+var Builtins = []struct {
+    Name string
+    Builtin *Builtin
+}{
+    {
+        "len",
+        &Builtin{Fn: func(out io.Writer, args ...Object) Object {
+            if len(args) != 1 {
+                return newError("wrong number of arguments. got=%d, want=1", len(args))
+            }
+
+            switch arg := args[0].(type) {
+                case *String:
+                    return &Integer{Value: int64(utf8.RuneCountInString(arg.Value))}
+                case *Array:
+                    return &Integer{Value: int64(len(arg.Elements))}
+                case *Hash:
+                    return &Integer{Value: int64(len(arg.Pairs))}
+                default:
+                    return newError("argument to `len` not supported, got %s", TypeName(args[0]))
+            }
+        }},
+    },
+    {
+        "puts",
+        &Builtin{Fn: func(out io.Writer, args ...Object) Object {
+            for _, arg := range args {
+                fmt.Fprintln(out, arg.Inspect())
+            }
+
+            return nil
+        }},
+    },
+    {
+        "first",
+        &Builtin{Fn: func(out io.Writer, args ...Object) Object {
+            if len(args) != 1 {
+                return newError("wrong number of arguments. got=%d, want=1", len(args))
+            }
+            if args[0].Type() != ARRAY_OBJ {
+                return newError("argument to `first` must be ARRAY, got %s", TypeName(args[0]))
+            }
+
+            arr := args[0].(*Array)
+            if len(arr.Elements) > 0 {
+                return arr.Elements[0]
+            }
+
+            return nil
+        }},
+    },
+    {
+        "last",
+        &Builtin{Fn: func(out io.Writer, args ...Object) Object {
+            if len(args) != 1 {
+                return newError("wrong number of arguments. got=%d, want=1", len(args))
+            }
+            if args[0].Type() != ARRAY_OBJ {
+                return newError("argument to `last` must be ARRAY, got %s", TypeName(args[0]))
+            }
+
+            arr := args[0].(*Array)
+            length := len(arr.Elements)
+            if length > 0 {
+                return arr.Elements[length-1]
+            }
+
+            return nil
+        }},
+    },
+    {
+        "rest",
+        &Builtin{Fn: func(out io.Writer, args ...Object) Object {
+            if len(args) != 1 {
+                return newError("wrong number of arguments. got=%d, want=1", len(args))
+            }
+            if args[0].Type() != ARRAY_OBJ {
+                return newError("argument to `rest` must be ARRAY, got %s", TypeName(args[0]))
+            }
+
+            arr := args[0].(*Array)
+            length := len(arr.Elements)
+            if length > 0 {
+                newElements := make([]Object, length-1)
+                copy(newElements, arr.Elements[1:length])
+                return &Array{Elements: newElements}
+            }
+
+            return nil
+        }},
+    },
+    {
+        "push",
+        &Builtin{Fn: func(out io.Writer, args ...Object) Object {
+            if len(args) != 2 {
+                return newError("wrong number of arguments. got=%d, want=2", len(args))
+            }
+            if args[0].Type() != ARRAY_OBJ {
+                return newError("argument to `push` must be ARRAY, got %s", TypeName(args[0]))
+            }
+
+            arr := args[0].(*Array)
+            length := len(arr.Elements)
+
+            newElements := make([]Object, length+1)
+            copy(newElements, arr.Elements)
+            newElements[length] = args[1]
+
+            return &Array{Elements: newElements}
+        }},
+    },
+    {
+        "type",
+        &Builtin{Fn: func(out io.Writer, args ...Object) Object {
+            if len(args) != 1 {
+                return newError("wrong number of arguments. got=%d, want=1", len(args))
+            }
+
+            return &String{Value: string(TypeName(args[0]))}
+        }},
+    },
+    {
+        "str",
+        &Builtin{Fn: func(out io.Writer, args ...Object) Object {
+            if len(args) != 1 {
+                return newError("wrong number of arguments. got=%d, want=1", len(args))
+            }
+            if str, ok := args[0].(*String); ok {
+                return str
+            }
+
+            return &String{Value: args[0].Inspect()}
+        }},
+    },
+    {
+        "int",
+        &Builtin{Fn: func(out io.Writer, args ...Object) Object {
+            if len(args) != 1 {
+                return newError("wrong number of arguments. got=%d, want=1", len(args))
+            }
+
+            switch arg := args[0].(type) {
+                case *Integer, *BigInt:
+                    return arg
+                case *Float:
+                    return floatToInteger(math.Trunc(arg.Value))
+                case *Boolean:
+                    if arg.Value {
+                        return &Integer{Value: 1}
+                    }
+                    return &Integer{Value: 0}
+                case *String:
+                    value, ok := new(big.Int).SetString(strings.TrimSpace(arg.Value), 0)
+                    if !ok {
+                        return newError("could not convert %q to INTEGER", arg.Value)
+                    }
+                    return IntegerFromBig(value)
+                default:
+                    return newError("argument to `int` not supported, got %s", TypeName(args[0]))
+            }
+        }},
+    },
+    {
+        "float",
+        &Builtin{Fn: func(out io.Writer, args ...Object) Object {
+            if len(args) != 1 {
+                return newError("wrong number of arguments. got=%d, want=1", len(args))
+            }
+
+            switch arg := args[0].(type) {
+                case *Float:
+                    return arg
+                case *Integer:
+                    return &Float{Value: float64(arg.Value)}
+                case *BigInt:
+                    value, _ := new(big.Float).SetInt(arg.Value).Float64()
+                    return &Float{Value: value}
+                case *Boolean:
+                    if arg.Value {
+                        return &Float{Value: 1}
+                    }
+                    return &Float{Value: 0}
+                case *String:
+                    value, err := strconv.ParseFloat(strings.TrimSpace(arg.Value), 64)
+                    if err != nil {
+                        return newError("could not convert %q to FLOAT", arg.Value)
+                    }
+                    return &Float{Value: value}
+                default:
+                    return newError("argument to `float` not supported, got %s", TypeName(args[0]))
+            }
+        }},
+    },
+    {
+        "floor",
+        &Builtin{Fn: func(out io.Writer, args ...Object) Object {
+            return roundNumber("floor", math.Floor, args)
+        }},
+    },
+    {
+        "ceil",
+        &Builtin{Fn: func(out io.Writer, args ...Object) Object {
+            return roundNumber("ceil", math.Ceil, args)
+        }},
+    },
+    {
+        "round",
+        &Builtin{Fn: func(out io.Writer, args ...Object) Object {
+            if len(args) != 2 {
+                return roundNumber("round", math.Round, args)
+            }
+
+            // round(x, digits) keeps a float rounded to the given decimals
+            var value float64
+            switch arg := args[0].(type) {
+                case *Integer:
+                    value = float64(arg.Value)
+                case *BigInt:
+                    value, _ = new(big.Float).SetInt(arg.Value).Float64()
+                case *Float:
+                    value = arg.Value
+                default:
+                    return newError("argument to `round` must be INTEGER or FLOAT, got %s", TypeName(args[0]))
+            }
+
+            var digits int64
+            switch arg := args[1].(type) {
+                case *Integer:
+                    digits = arg.Value
+                case *BigInt:
+                    // more digits than a float has keep it as it is, fewer
+                    // round it away
+                    if arg.Value.Sign() > 0 {
+                        return &Float{Value: value}
+                    }
+                    return &Float{Value: 0}
+                default:
+                    return newError("second argument to `round` must be INTEGER, got %s", TypeName(args[1]))
+            }
+
+            scale := math.Pow(10, float64(digits))
+            return &Float{Value: math.Round(value*scale) / scale}
+        }},
+    },
+    {
+        "range",
+        &Builtin{Fn: func(out io.Writer, args ...Object) Object {
+            if len(args) < 1 || len(args) > 3 {
+                return newError("wrong number of arguments. got=%d, want=1..3", len(args))
+            }
+
+            bounds := make([]int64, len(args))
+            for i, arg := range args {
+                if bi, ok := arg.(*BigInt); ok {
+                    return newError("argument to `range` out of range: %s", bi.Inspect())
+                }
+                integer, ok := arg.(*Integer)
+                if !ok {
+                    return newError("arguments to `range` must be INTEGER, got %s", TypeName(arg))
+                }
+                bounds[i] = integer.Value
+            }
+
+            // range(end) counts from 0, range(start, end) counts by 1
+            r := &Range{End: bounds[0], Step: 1}
+            if len(bounds) > 1 {
+                r.Start, r.End = bounds[0], bounds[1]
+            }
+            if len(bounds) > 2 {
+                r.Step = bounds[2]
+            }
+            if r.Step == 0 {
+                return newError("`range` step must not be zero")
+            }
+
+            return r
+        }},
+    },
+}
+
+// roundNumber implements floor, ceil and round, which turn a float into the
+// nearest integer in their direction. Integers are returned unchanged.
+func roundNumber(name string, round func(float64) float64, args []Object) Object {
+    if len(args) != 1 {
+        return newError("wrong number of arguments. got=%d, want=1", len(args))
+    }
+
+    switch arg := args[0].(type) {
+        case *Integer, *BigInt:
+            return arg
+        case *Float:
+            return floatToInteger(round(arg.Value))
+        default:
+            return newError("argument to `%s` must be INTEGER or FLOAT, got %s", name, TypeName(args[0]))
+    }
+}
+
+// floatToInteger converts an integral float to an Integer, or a BigInt when
+// it is outside the int64 range. It fails for NaN and infinities.
+func floatToInteger(value float64) Object {
+    if math.IsNaN(value) || math.IsInf(value, 0) {
+        return newError("could not convert %s to INTEGER", (&Float{Value: value}).Inspect())
+    }
+
+    if value < math.MinInt64 || value >= math.MaxInt64 {
+        integer, _ := big.NewFloat(value).Int(nil)
+        return IntegerFromBig(integer)
+    }
+
+    return &Integer{Value: int64(value)}
+}
+
+func GetBuiltinByName(name string) *Builtin {
+    for _, def := range Builtins {
+        if def.Name == name {
+            return def.Builtin
+        }
+    }
+
+    return nil
+}
+
+func newError(format string, a ...interface{}) *Error {
+    return &Error{Message: fmt.Sprintf(format, a...)}
+}
@@ -3,19 +3,28 @@ package object
 type Enviroment struct {
     store map[string]Object
     outer *Enviroment
+    options *Options //shared by the enviroments of a program
 }
 
 func NewEnviroment() *Enviroment {
-    return &Enviroment{store: make(map[string]Object), outer: nil}
+    return NewEnviromentWithOptions(Options{})
+}
+
+func NewEnviromentWithOptions(options Options) *Enviroment {
+    return &Enviroment{store: make(map[string]Object), outer: nil, options: &options}
 }
 
 func NewEnclosedEnviorment(outer *Enviroment) *Enviroment {
-    env := NewEnviroment()
-    env.outer = outer
+    env := &Enviroment{store: make(map[string]Object), outer: outer, options: outer.options}
 
     return env
 }
 
+// Options returns the options of the program the enviroment belongs to.
+func (e *Enviroment) Options() Options {
+    return *e.options
+}
+
 func (e *Enviroment) Get(name string) (Object, bool) {
     obj, ok := e.store[name]
 
@@ -29,3 +38,18 @@ func (e *Enviroment) Get(name string) (Object, bool) {
 func (e *Enviroment) Set(name string, obj Object) {
     e.store[name] = obj
 }
+
+// Assign updates the nearest binding of name, in this enviroment or an
+// enclosing one. It returns false if name is not bound anywhere.
+func (e *Enviroment) Assign(name string, obj Object) bool {
+    if _, ok := e.store[name]; ok {
+        e.store[name] = obj
+        return true
+    }
+
+    if e.outer != nil {
+        return e.outer.Assign(name, obj)
+    }
+
+    return false
+}
@@ -0,0 +1,118 @@
+package object
+
+import "fmt"
+
+const (
+    ITERATOR_OBJ = "ITERATOR"
+    RANGE_OBJ = "RANGE"
+)
+
+// Iterable is implemented by the objects a for-in loop can iterate over.
+type Iterable interface {
+    Iterator() *Iterator
+}
+
+// Iterator yields the elements of an Iterable one at a time. Next returns
+// the key and the value of the next element, or false once there are none
+// left. Sequences use the element's index as its key.
+//
+// A for-in loop with two variables binds the key and the value. A loop with
+// a single variable binds the value, or the key if SingleKey is set, which
+// hashes do.
+type Iterator struct {
+    next func() (Object, Object, bool)
+
+    SingleKey bool
+}
+
+func NewIterator(next func() (Object, Object, bool)) *Iterator {
+    return &Iterator{next: next}
+}
+
+func (it *Iterator) Next() (Object, Object, bool) {
+    return it.next()
+}
+
+func (it *Iterator) Inspect() string {
+    return "iterator"
+}
+
+func (it *Iterator) Type() ObjectType {
+    return ITERATOR_OBJ
+}
+
+func (a *Array) Iterator() *Iterator {
+    i := 0
+    return NewIterator(func() (Object, Object, bool) {
+        if i >= len(a.Elements) {
+            return nil, nil, false
+        }
+
+        i++
+        return &Integer{Value: int64(i - 1)}, a.Elements[i-1], true
+    })
+}
+
+func (h *Hash) Iterator() *Iterator {
+    keys := h.Keys[:len(h.Keys):len(h.Keys)]
+    i := 0
+    it := NewIterator(func() (Object, Object, bool) {
+        if i >= len(keys) {
+            return nil, nil, false
+        }
+
+        pair := h.Pairs[keys[i]]
+        i++
+        return pair.Key, pair.Value, true
+    })
+    it.SingleKey = true
+
+    return it
+}
+
+// Iterator yields the characters of the string, one rune at a time.
+func (s *String) Iterator() *Iterator {
+    runes := []rune(s.Value)
+    i := 0
+    return NewIterator(func() (Object, Object, bool) {
+        if i >= len(runes) {
+            return nil, nil, false
+        }
+
+        i++
+        return &Integer{Value: int64(i - 1)}, &String{Value: string(runes[i-1])}, true
+    })
+}
+
+// Range is the integer sequence created by the range builtin. Its values
+// are computed while iterating instead of being stored.
+type Range struct {
+    Start int64
+    End int64
+    Step int64
+}
+
+func (r *Range) Inspect() string {
+    return fmt.Sprintf("range(%d, %d, %d)", r.Start, r.End, r.Step)
+}
+
+func (r *Range) Type() ObjectType {
+    return RANGE_OBJ
+}
+
+func (r *Range) Iterator() *Iterator {
+    value, index := r.Start, int64(0)
+    done := false
+    return NewIterator(func() (Object, Object, bool) {
+        if done || (r.Step > 0 && value >= r.End) || (r.Step < 0 && value <= r.End) {
+            return nil, nil, false
+        }
+
+        current := value
+        value += r.Step
+        done = (value < current) != (r.Step < 0) //the value overflowed int64
+        index++
+
+        return &Integer{Value: index - 1}, &Integer{Value: current}, true
+    })
+}
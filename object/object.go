@@ -3,7 +3,12 @@ package object
 import (
 	"bytes"
 	"fmt"
+	"hash/fnv"
 	"interpreter/ast"
+	"interpreter/code"
+	"io"
+	"math/big"
+	"strconv"
 	"strings"
 )
 
@@ -11,12 +16,18 @@ type ObjectType string
 
 const (
     INTEGER_OBJ = "INTEGER"
+    FLOAT_OBJ = "FLOAT"
+    BIGINT_OBJ = "BIGINT"
     BOOLEAN_OBJ = "BOOLEAN"
     NULL_OBJ = "NULL"
-    RETURN_VALUE_OBJ = "RETURN_VALUE"
     ERRROR_OBJ = "ERROR"
     FUNCTION_OBJ = "FUNCTION"
     STRING_OBJ = "STRING"
+    ARRAY_OBJ = "ARRAY"
+    BUILTIN_OBJ = "BUILTIN"
+    HASH_OBJ = "HASH"
+    COMPILED_FUNCTION_OBJ = "COMPILED_FUNCTION"
+    CELL_OBJ = "CELL"
 )
 
 type Object interface {
@@ -24,6 +35,29 @@ type Object interface {
     Inspect() string
 }
 
+// TypeName is the type of obj as scripts see it, in type() and in error
+// messages. Big integers are an implementation detail, so they are INTEGER.
+func TypeName(obj Object) ObjectType {
+    if obj.Type() == BIGINT_OBJ {
+        return INTEGER_OBJ
+    }
+
+    return obj.Type()
+}
+
+// HashKey identifies a hashable object. Objects of the same type and value
+// always produce the same HashKey.
+type HashKey struct {
+    Type ObjectType
+    Value uint64
+}
+
+// Hashable is implemented by objects that can be used as hash keys.
+type Hashable interface {
+    Object
+    HashKey() HashKey
+}
+
 type Integer struct {
     Value int64
 }
@@ -36,6 +70,60 @@ func (i *Integer) Type() ObjectType {
     return INTEGER_OBJ
 }
 
+func (i *Integer) HashKey() HashKey {
+    return HashKey{Type: i.Type(), Value: uint64(i.Value)}
+}
+
+// BigInt holds integers that do not fit in an int64. Values that fit are
+// always represented as an Integer, use IntegerFromBig to get that right.
+type BigInt struct {
+    Value *big.Int
+}
+
+func (b *BigInt) Inspect() string {
+    return b.Value.String()
+}
+
+func (b *BigInt) Type() ObjectType {
+    return BIGINT_OBJ
+}
+
+func (b *BigInt) HashKey() HashKey {
+    h := fnv.New64a()
+    h.Write([]byte(b.Value.String()))
+
+    return HashKey{Type: b.Type(), Value: h.Sum64()}
+}
+
+// IntegerFromBig returns an Integer when the value fits in an int64 and a
+// BigInt otherwise.
+func IntegerFromBig(value *big.Int) Object {
+    if value.IsInt64() {
+        return &Integer{Value: value.Int64()}
+    }
+
+    return &BigInt{Value: value}
+}
+
+type Float struct {
+    Value float64
+}
+
+// Inspect always shows a fraction or an exponent, so floats with integral
+// values can be told apart from integers.
+func (f *Float) Inspect() string {
+    s := strconv.FormatFloat(f.Value, 'g', -1, 64)
+    if !strings.ContainsAny(s, ".eIN") {
+        s += ".0"
+    }
+
+    return s
+}
+
+func (f *Float) Type() ObjectType {
+    return FLOAT_OBJ
+}
+
 type Boolean struct {
     Value bool
 }
@@ -48,6 +136,16 @@ func (b *Boolean) Type() ObjectType {
     return BOOLEAN_OBJ
 }
 
+func (b *Boolean) HashKey() HashKey {
+    var value uint64
+
+    if b.Value {
+        value = 1
+    }
+
+    return HashKey{Type: b.Type(), Value: value}
+}
+
 type Null struct {}
 
 func (n *Null) Inspect() string {
@@ -58,30 +156,28 @@ func (n *Null) Type() ObjectType {
     return NULL_OBJ
 }
 
-type RetrunValue struct {
-    Value Object
-}
-
-func (rv *RetrunValue) Inspect() string {
-    return rv.Value.Inspect()
-}
-
-func (rv *RetrunValue) Type() ObjectType {
-    return RETURN_VALUE_OBJ
-}
-
 type Function struct {
     Parameters []*ast.Indentifier
+    Defaults []ast.Expression //default value of each parameter, nil if it has none
+    Rest *ast.Indentifier
     Body *ast.BlockStatement
     Env *Enviroment
+    Name string //name of the let binding the function was defined in, if any
 }
 
 func (f *Function) Inspect() string {
     var out bytes.Buffer
     var params []string
 
-    for _, p := range f.Parameters {
-        params = append(params, p.String())
+    for i, p := range f.Parameters {
+        if i < len(f.Defaults) && f.Defaults[i] != nil {
+            params = append(params, p.String() + " = " + f.Defaults[i].String())
+        } else {
+            params = append(params, p.String())
+        }
+    }
+    if f.Rest != nil {
+        params = append(params, "..." + f.Rest.String())
     }
 
     out.WriteString("fn(")
@@ -120,3 +216,180 @@ func (s *String) Inspect() string {
 func (s *String) Type() ObjectType {
     return STRING_OBJ
 }
+
+func (s *String) HashKey() HashKey {
+    h := fnv.New64a()
+    h.Write([]byte(s.Value))
+
+    return HashKey{Type: s.Type(), Value: h.Sum64()}
+}
+
+// inspect returns the Inspect output of obj. Arrays and hashes that contain
+// themselves print the repeated collection as [...] or {...}; visiting holds
+// the collections being printed.
+func inspect(obj Object, visiting map[Object]bool) string {
+    switch obj := obj.(type) {
+        case *Array:
+            if visiting[obj] {
+                return "[...]"
+            }
+            visiting[obj] = true
+            defer delete(visiting, obj)
+            return obj.inspect(visiting)
+        case *Hash:
+            if visiting[obj] {
+                return "{...}"
+            }
+            visiting[obj] = true
+            defer delete(visiting, obj)
+            return obj.inspect(visiting)
+        default:
+            return obj.Inspect()
+    }
+}
+
+type Array struct {
+    Elements []Object
+}
+
+func (a *Array) Inspect() string {
+    return inspect(a, map[Object]bool{})
+}
+
+func (a *Array) inspect(visiting map[Object]bool) string {
+    var out bytes.Buffer
+    elements := []string{}
+
+    for _, e := range a.Elements {
+        elements = append(elements, inspect(e, visiting))
+    }
+
+    out.WriteString("[")
+    out.WriteString(strings.Join(elements, ", "))
+    out.WriteString("]")
+
+    return out.String()
+}
+
+func (a *Array) Type() ObjectType {
+    return ARRAY_OBJ
+}
+
+// BuiltinFunction implements a builtin. Builtins that print write to out.
+type BuiltinFunction func(out io.Writer, args ...Object) Object
+
+type Builtin struct {
+    Fn BuiltinFunction
+}
+
+func (b *Builtin) Inspect() string {
+    return "builtin function"
+}
+
+func (b *Builtin) Type() ObjectType {
+    return BUILTIN_OBJ
+}
+
+type HashPair struct {
+    Key Object
+    Value Object
+}
+
+type Hash struct {
+    Pairs map[HashKey]HashPair
+    Keys []HashKey //insertion order of Pairs
+}
+
+func NewHash() *Hash {
+    return &Hash{Pairs: make(map[HashKey]HashPair)}
+}
+
+func (h *Hash) Get(key Hashable) (Object, bool) {
+    pair, ok := h.Pairs[key.HashKey()]
+    if !ok {
+        return nil, false
+    }
+
+    return pair.Value, true
+}
+
+func (h *Hash) Set(key Hashable, value Object) {
+    hashKey := key.HashKey()
+    if _, ok := h.Pairs[hashKey]; !ok {
+        h.Keys = append(h.Keys, hashKey)
+    }
+
+    h.Pairs[hashKey] = HashPair{Key: key, Value: value}
+}
+
+func (h *Hash) Inspect() string {
+    return inspect(h, map[Object]bool{})
+}
+
+func (h *Hash) inspect(visiting map[Object]bool) string {
+    var out bytes.Buffer
+    pairs := []string{}
+
+    for _, key := range h.Keys {
+        pair := h.Pairs[key]
+        pairs = append(pairs, fmt.Sprintf("%s: %s", pair.Key.Inspect(), inspect(pair.Value, visiting)))
+    }
+
+    out.WriteString("{")
+    out.WriteString(strings.Join(pairs, ", "))
+    out.WriteString("}")
+
+    return out.String()
+}
+
+func (h *Hash) Type() ObjectType {
+    return HASH_OBJ
+}
+
+type CompiledFunction struct {
+    Instructions code.Instructions
+    NumLocals int
+    NumParameters int
+    NumDefaults int //number of trailing parameters with a default value
+    Rest bool //whether a rest parameter follows the others
+    Name string
+    LocalNames []string //names of the local slots, used in runtime errors
+    FreeNames []string //names of the free variables, used in runtime errors
+}
+
+func (cf *CompiledFunction) Inspect() string {
+    return fmt.Sprintf("CompiledFunction[%p]", cf)
+}
+
+func (cf *CompiledFunction) Type() ObjectType {
+    return COMPILED_FUNCTION_OBJ
+}
+
+// Cell boxes a local variable of a compiled function that closures both
+// capture and assign, so they all share one binding instead of copies.
+type Cell struct {
+    Value Object
+}
+
+func (c *Cell) Inspect() string {
+    return c.Value.Inspect()
+}
+
+func (c *Cell) Type() ObjectType {
+    return CELL_OBJ
+}
+
+type Closure struct {
+    Fn *CompiledFunction
+    Free []Object
+}
+
+func (c *Closure) Inspect() string {
+    return fmt.Sprintf("Closure[%p]", c)
+}
+
+// Type reports closures as functions so scripts see the same types whether
+// they run on the evaluator or the vm.
+func (c *Closure) Type() ObjectType {
+    return FUNCTION_OBJ
+}
@@ -0,0 +1,26 @@
+package object
+
+import (
+	"io"
+	"os"
+)
+
+// Options configures a run of a program. The evaluator takes them from the
+// enviroment the program runs in, the vm from its constructor.
+type Options struct {
+    // Output receives what scripts print; nil means standard output.
+    Output io.Writer
+
+    // CheckOverflow makes integer arithmetic report int64 overflow as an
+    // error instead of promoting the result to a BigInt.
+    CheckOverflow bool
+}
+
+// Out returns the writer scripts print to.
+func (o Options) Out() io.Writer {
+    if o.Output == nil {
+        return os.Stdout
+    }
+
+    return o.Output
+}
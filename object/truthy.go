@@ -0,0 +1,47 @@
+package object
+
+// Truthable is implemented by objects that decide whether they are true
+// when used as a condition. Objects that don't implement it are true.
+//
+// null, false, zero, the empty string and empty collections are false.
+type Truthable interface {
+    Object
+    Truthy() bool
+}
+
+func (n *Null) Truthy() bool {
+    return false
+}
+
+func (b *Boolean) Truthy() bool {
+    return b.Value
+}
+
+func (i *Integer) Truthy() bool {
+    return i.Value != 0
+}
+
+func (bi *BigInt) Truthy() bool {
+    return bi.Value.Sign() != 0
+}
+
+func (f *Float) Truthy() bool {
+    return f.Value != 0
+}
+
+func (s *String) Truthy() bool {
+    return s.Value != ""
+}
+
+func (a *Array) Truthy() bool {
+    return len(a.Elements) > 0
+}
+
+func (h *Hash) Truthy() bool {
+    return len(h.Pairs) > 0
+}
+
+// Truthy reports whether the range has any values.
+func (r *Range) Truthy() bool {
+    return (r.Step > 0 && r.Start < r.End) || (r.Step < 0 && r.Start > r.End)
+}
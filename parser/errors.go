@@ -0,0 +1,22 @@
+package parser
+
+import (
+	"fmt"
+	"interpreter/token"
+)
+
+// ParseError describes a single syntax error found while parsing.
+type ParseError struct {
+    Pos token.Position
+    Expected []token.TokenType //token types that would have been accepted, if known
+    Actual token.Token //the token that caused the error
+    Message string
+}
+
+func (e *ParseError) Error() string {
+    if !e.Pos.IsValid() {
+        return e.Message
+    }
+
+    return fmt.Sprintf("%s: %s", e.Pos, e.Message)
+}
@@ -5,39 +5,76 @@ import (
 	"interpreter/ast"
 	"interpreter/lexer"
 	"interpreter/token"
+	"math/big"
 	"strconv"
+	"unicode/utf8"
 )
 
 const (
     _ int = iota
     LOWEST
+    ASSIGN
+    LOGICAL_OR
+    LOGICAL_AND
     EQUALS
     LESSGREATER
+    BIT_OR
+    BIT_XOR
+    BIT_AND
+    SHIFT
     SUM
     PRODUCT
     PREFIX
+    POWER
     CALL
+    INDEX
 )
 
 var precedences = map[token.TokenType]int{
+    token.ASSIGN: ASSIGN,
+    token.PLUS_ASSIGN: ASSIGN,
+    token.MINUS_ASSIGN: ASSIGN,
+    token.ASTERISK_ASSIGN: ASSIGN,
+    token.SLASH_ASSIGN: ASSIGN,
     token.EQ: EQUALS,
     token.NOT_EQ: EQUALS,
     token.LT: LESSGREATER,
     token.GT: LESSGREATER,
+    token.LT_EQ: LESSGREATER,
+    token.GT_EQ: LESSGREATER,
+    token.AND: LOGICAL_AND,
+    token.OR: LOGICAL_OR,
     token.PLUS: SUM,
     token.MINUS: SUM,
     token.SLASH: PRODUCT,
     token.ASTERISK: PRODUCT,
+    token.PERCENT: PRODUCT,
+    token.POWER: POWER,
+    token.BIT_OR: BIT_OR,
+    token.BIT_XOR: BIT_XOR,
+    token.BIT_AND: BIT_AND,
+    token.SHL: SHIFT,
+    token.SHR: SHIFT,
     token.LPAREN: CALL,
+    token.LBRACKET: INDEX,
+}
+
+// rightAssociative lists the infix operators that group from the right, so
+// 2 ** 3 ** 2 parses as 2 ** (3 ** 2).
+var rightAssociative = map[token.TokenType]bool{
+    token.POWER: true,
 }
 
 type Parser struct {
     l *lexer.Lexer
     curToken token.Token
     peekToken token.Token
-    errors []string
+    errors []*ParseError
     prefixParserFns map[token.TokenType]prefixParserFn
     infixParserFns map[token.TokenType]infixParserFn
+
+    loopDepth int //number of loops around the current token, reset by fn
+    inDefault bool //whether the current token is in a parameter's default value, reset by fn
 }
 
 type (
@@ -48,31 +85,52 @@ type (
 func New(l *lexer.Lexer) *Parser {
     p := &Parser{
         l: l,
-        errors: []string{},
+        errors: []*ParseError{},
     }
 
     p.prefixParserFns = make(map[token.TokenType]prefixParserFn)
     p.registerPrefix(token.IDENT, p.parseIdenfier)
     p.registerPrefix(token.INT, p.parseIntegerLiteral)
+    p.registerPrefix(token.FLOAT, p.parseFloatLiteral)
     p.registerPrefix(token.BANG, p.parsePrefixExpression)
     p.registerPrefix(token.MINUS, p.parsePrefixExpression)
+    p.registerPrefix(token.BIT_NOT, p.parsePrefixExpression)
     p.registerPrefix(token.TRUE, p.parseBoolean)
     p.registerPrefix(token.FALSE, p.parseBoolean)
     p.registerPrefix(token.LPAREN, p.parseGroupedExpression)
     p.registerPrefix(token.IF, p.parseIfExpression)
     p.registerPrefix(token.FUNCTION, p.parseFunctionLiteral)
     p.registerPrefix(token.STRING, p.parseStringLiteral)
+    p.registerPrefix(token.LBRACKET, p.parseArrayLiteral)
+    p.registerPrefix(token.LBRACE, p.parseHashLiteral)
 
     p.infixParserFns = make(map[token.TokenType]infixParserFn)
     p.registerInfix(token.PLUS, p.parseInfixExpression)
     p.registerInfix(token.MINUS, p.parseInfixExpression)
     p.registerInfix(token.SLASH, p.parseInfixExpression)
     p.registerInfix(token.ASTERISK, p.parseInfixExpression)
+    p.registerInfix(token.PERCENT, p.parseInfixExpression)
+    p.registerInfix(token.POWER, p.parseInfixExpression)
+    p.registerInfix(token.BIT_AND, p.parseInfixExpression)
+    p.registerInfix(token.BIT_OR, p.parseInfixExpression)
+    p.registerInfix(token.BIT_XOR, p.parseInfixExpression)
+    p.registerInfix(token.SHL, p.parseInfixExpression)
+    p.registerInfix(token.SHR, p.parseInfixExpression)
     p.registerInfix(token.EQ, p.parseInfixExpression)
     p.registerInfix(token.NOT_EQ, p.parseInfixExpression)
     p.registerInfix(token.LT, p.parseInfixExpression)
     p.registerInfix(token.GT, p.parseInfixExpression)
+    p.registerInfix(token.LT_EQ, p.parseInfixExpression)
+    p.registerInfix(token.GT_EQ, p.parseInfixExpression)
+    p.registerInfix(token.AND, p.parseInfixExpression)
+    p.registerInfix(token.OR, p.parseInfixExpression)
+    p.registerInfix(token.ASSIGN, p.parseAssignExpression)
+    p.registerInfix(token.PLUS_ASSIGN, p.parseAssignExpression)
+    p.registerInfix(token.MINUS_ASSIGN, p.parseAssignExpression)
+    p.registerInfix(token.ASTERISK_ASSIGN, p.parseAssignExpression)
+    p.registerInfix(token.SLASH_ASSIGN, p.parseAssignExpression)
     p.registerInfix(token.LPAREN, p.parseCallExpression)
+    p.registerInfix(token.LBRACKET, p.parseIndexExpression)
 
     p.nextToken()
     p.nextToken()
@@ -88,7 +146,7 @@ func (p *Parser) parseBoolean() ast.Expression {
     return &ast.Boolean{Token: p.curToken, Value: p.curToken.Type == token.TRUE}
 }
 
-func (p *Parser) Errors() []string {
+func (p *Parser) Errors() []*ParseError {
     return p.errors
 }
 
@@ -113,14 +171,67 @@ func (p *Parser) ParseProgram () *ast.Program {
     return program
 }
 
+// parseStatement parses a single statement. If the statement contains a
+// syntax error it is discarded and the parser skips ahead to the end of it,
+// so parsing can continue with the next statement.
 func (p *Parser) parseStatement() ast.Statement {
+    errCount := len(p.errors)
+
+    var stmt ast.Statement
     switch p.curToken.Type {
     case token.LET:
-        return p.parseLetStatement()
+        stmt = p.parseLetStatement()
     case token.RETURN:
-        return p.parseReturnStatement()
+        stmt = p.parseReturnStatement()
+    case token.WHILE:
+        stmt = p.parseWhileStatement()
+    case token.FOR:
+        stmt = p.parseForStatement()
+    case token.BREAK:
+        stmt = p.parseBreakStatement()
+    case token.CONTINUE:
+        stmt = p.parseContinueStatement()
     default:
-        return p.parseExpressionStatement()
+        stmt = p.parseExpressionStatement()
+    }
+
+    if len(p.errors) > errCount {
+        p.synchronize()
+        return nil
+    }
+
+    return stmt
+}
+
+// synchronize discards tokens until the current token ends a statement: a
+// semicolon, a closing brace matching one opened while skipping, or the
+// token before the closing brace of the enclosing block or the start of a
+// new statement.
+func (p *Parser) synchronize() {
+    depth := 0
+    for p.curToken.Type != token.EOF {
+        switch p.curToken.Type {
+        case token.LBRACE:
+            depth++
+        case token.RBRACE:
+            depth--
+            if depth <= 0 {
+                return
+            }
+        case token.SEMICOLON:
+            if depth == 0 {
+                return
+            }
+        }
+
+        if depth == 0 {
+            switch p.peekToken.Type {
+            case token.RBRACE, token.EOF, token.LET, token.RETURN,
+                token.WHILE, token.FOR, token.BREAK, token.CONTINUE:
+                return
+            }
+        }
+        p.nextToken()
     }
 }
 
@@ -139,6 +250,10 @@ func (p *Parser) parseLetStatement() *ast.LetStatemet {
     p.nextToken()
 
     stmt.Value = p.parseExpression(LOWEST)
+    if fl, ok := stmt.Value.(*ast.FunctionLiteral); ok {
+        fl.Name = stmt.Name.Value
+    }
+
     if p.peekToken.Type == token.SEMICOLON {
         p.nextToken()
     }
@@ -148,6 +263,10 @@ func (p *Parser) parseLetStatement() *ast.LetStatemet {
 
 func (p *Parser) parseReturnStatement() *ast.ReturnStatement {
     stmt := &ast.ReturnStatement{Token: p.curToken}
+    if p.inDefault {
+        p.addError(p.curToken, nil, "return in a default parameter value")
+        return nil
+    }
     p.nextToken()
 
     stmt.ReturnValue = p.parseExpression(LOWEST)
@@ -158,6 +277,157 @@ func (p *Parser) parseReturnStatement() *ast.ReturnStatement {
     return stmt
 }
 
+func (p *Parser) parseWhileStatement() *ast.WhileStatement {
+    stmt := &ast.WhileStatement{Token: p.curToken}
+    if !p.expectPeek(token.LPAREN) {
+        return nil
+    }
+
+    p.nextToken()
+    stmt.Condition = p.parseExpression(LOWEST)
+
+    if !p.expectPeek(token.RPAREN) {
+        return nil
+    }
+
+    if !p.expectPeek(token.LBRACE) {
+        return nil
+    }
+
+    stmt.Body = p.parseLoopBody()
+    if p.peekToken.Type == token.SEMICOLON {
+        p.nextToken()
+    }
+
+    return stmt
+}
+
+func (p *Parser) parseForStatement() ast.Statement {
+    stmt := &ast.ForStatement{Token: p.curToken}
+    if !p.expectPeek(token.LPAREN) {
+        return nil
+    }
+    p.nextToken()
+
+    if p.curToken.Type == token.IDENT && (p.peekToken.Type == token.IN || p.peekToken.Type == token.COMMA) {
+        return p.parseForInStatement(stmt.Token)
+    }
+
+    if p.curToken.Type != token.SEMICOLON {
+        errCount := len(p.errors)
+        if p.curToken.Type == token.LET {
+            stmt.Init = p.parseLetStatement()
+        } else {
+            stmt.Init = p.parseExpressionStatement()
+        }
+        if len(p.errors) > errCount {
+            return nil
+        }
+
+        if p.curToken.Type != token.SEMICOLON && !p.expectPeek(token.SEMICOLON) {
+            return nil
+        }
+    }
+    p.nextToken()
+
+    if p.curToken.Type != token.SEMICOLON {
+        stmt.Condition = p.parseExpression(LOWEST)
+        if !p.expectPeek(token.SEMICOLON) {
+            return nil
+        }
+    }
+    p.nextToken()
+
+    if p.curToken.Type != token.RPAREN {
+        stmt.Step = p.parseExpression(LOWEST)
+        if !p.expectPeek(token.RPAREN) {
+            return nil
+        }
+    }
+
+    if !p.expectPeek(token.LBRACE) {
+        return nil
+    }
+
+    stmt.Body = p.parseLoopBody()
+    if p.peekToken.Type == token.SEMICOLON {
+        p.nextToken()
+    }
+
+    return stmt
+}
+
+func (p *Parser) parseForInStatement(forToken token.Token) *ast.ForInStatement {
+    stmt := &ast.ForInStatement{Token: forToken}
+    stmt.Value = &ast.Indentifier{Token: p.curToken, Value: p.curToken.Literal}
+
+    if p.peekToken.Type == token.COMMA {
+        p.nextToken()
+        if !p.expectPeek(token.IDENT) {
+            return nil
+        }
+        stmt.Key = stmt.Value
+        stmt.Value = &ast.Indentifier{Token: p.curToken, Value: p.curToken.Literal}
+    }
+
+    if !p.expectPeek(token.IN) {
+        return nil
+    }
+    p.nextToken()
+
+    stmt.Iterable = p.parseExpression(LOWEST)
+
+    if !p.expectPeek(token.RPAREN) {
+        return nil
+    }
+
+    if !p.expectPeek(token.LBRACE) {
+        return nil
+    }
+
+    stmt.Body = p.parseLoopBody()
+    if p.peekToken.Type == token.SEMICOLON {
+        p.nextToken()
+    }
+
+    return stmt
+}
+
+func (p *Parser) parseLoopBody() *ast.BlockStatement {
+    p.loopDepth++
+    defer func() { p.loopDepth-- }()
+
+    return p.parseBlockStatement()
+}
+
+func (p *Parser) parseBreakStatement() *ast.BreakStatement {
+    stmt := &ast.BreakStatement{Token: p.curToken}
+    if p.loopDepth == 0 {
+        p.addError(p.curToken, nil, "break outside of a loop")
+        return nil
+    }
+
+    if p.peekToken.Type == token.SEMICOLON {
+        p.nextToken()
+    }
+
+    return stmt
+}
+
+func (p *Parser) parseContinueStatement() *ast.ContinueStatement {
+    stmt := &ast.ContinueStatement{Token: p.curToken}
+    if p.loopDepth == 0 {
+        p.addError(p.curToken, nil, "continue outside of a loop")
+        return nil
+    }
+
+    if p.peekToken.Type == token.SEMICOLON {
+        p.nextToken()
+    }
+
+    return stmt
+}
+
 func (p *Parser) parseExpressionStatement() *ast.ExpressionStatement {
     stmt := &ast.ExpressionStatement{Token: p.curToken} 
 
@@ -206,10 +476,16 @@ func (p *Parser) parseIntegerLiteral() ast.Expression {
 
     value, err := strconv.ParseInt(p.curToken.Literal, 0, 64)
     if err != nil {
-        msg := fmt.Sprintf("coulnd not parse %q as integer", p.curToken.Literal)
-        p.errors = append(p.errors, msg)
+        bigValue, ok := new(big.Int).SetString(p.curToken.Literal, 0)
+        if !ok {
+            msg := fmt.Sprintf("coulnd not parse %q as integer", p.curToken.Literal)
+            p.addError(p.curToken, nil, msg)
 
-        return nil
+            return nil
+        }
+
+        lit.Big = bigValue
+        return lit
     }
 
     lit.Value = value
@@ -217,6 +493,22 @@ func (p *Parser) parseIntegerLiteral() ast.Expression {
     return lit
 }
 
+func (p *Parser) parseFloatLiteral() ast.Expression {
+    lit := &ast.FloatLiteral{Token: p.curToken}
+
+    value, err := strconv.ParseFloat(p.curToken.Literal, 64)
+    if err != nil {
+        msg := fmt.Sprintf("could not parse %q as float", p.curToken.Literal)
+        p.addError(p.curToken, nil, msg)
+
+        return nil
+    }
+
+    lit.Value = value
+
+    return lit
+}
+
 func (p *Parser) parsePrefixExpression() ast.Expression {
     expression := &ast.PrefixExpression{
         Token: p.curToken,
@@ -235,12 +527,39 @@ func (p *Parser) parseInfixExpression(left ast.Expression) ast.Expression {
         Left: left,
     }
     precendece := p.curPrecendence()
+    if rightAssociative[p.curToken.Type] {
+        // parsing the right operand one level lower lets it take another
+        // operator of the same precedence
+        precendece--
+    }
     p.nextToken()
     expression.Right = p.parseExpression(precendece)
 
     return expression
 }
 
+// parseAssignExpression parses `target = value` and the compound forms.
+// Assignment groups from the right, so a = b = 1 assigns 1 to both.
+func (p *Parser) parseAssignExpression(left ast.Expression) ast.Expression {
+    expression := &ast.AssignExpression{
+        Token: p.curToken,
+        Target: left,
+        Operator: p.curToken.Literal,
+    }
+
+    switch left.(type) {
+    case *ast.Indentifier, *ast.IndexExpression:
+    default:
+        p.addError(p.curToken, nil, fmt.Sprintf("cannot assign to %s", left))
+        return nil
+    }
+
+    p.nextToken()
+    expression.Value = p.parseExpression(LOWEST)
+
+    return expression
+}
+
 func (p *Parser) parseIfExpression() ast.Expression {
     expression := &ast.IfExpression{Token: p.curToken}
     if !p.expectPeek(token.LPAREN) {
@@ -282,6 +601,9 @@ func (p *Parser) parseBlockStatement() *ast.BlockStatement {
         }
         p.nextToken()
     }
+    if p.curToken.Type == token.RBRACE {
+        block.Rbrace = p.curToken
+    }
 
     return block
 }
@@ -293,7 +615,14 @@ func (p *Parser) parseFunctionLiteral() ast.Expression {
         return nil
     }
 
-    lit.Parameters = p.parseFunctionParameters()
+    // a function is outside of any loop or default value around the literal
+    loopDepth, inDefault := p.loopDepth, p.inDefault
+    p.loopDepth, p.inDefault = 0, false
+    defer func() { p.loopDepth, p.inDefault = loopDepth, inDefault }()
+
+    if !p.parseFunctionParameters(lit) {
+        return nil
+    }
 
     if !p.expectPeek(token.LBRACE) {
         return nil
@@ -304,60 +633,201 @@ func (p *Parser) parseFunctionLiteral() ast.Expression {
     return lit
 }
 
-func (p *Parser) parseFunctionParameters() []*ast.Indentifier {
-    identifiers := []*ast.Indentifier{}
+// parseFunctionParameters parses the parameter list of lit. Parameters
+// with a default value have to follow those without one, and a rest
+// parameter can only be the last one.
+func (p *Parser) parseFunctionParameters(lit *ast.FunctionLiteral) bool {
+    lit.Parameters = []*ast.Indentifier{}
+    lit.Defaults = []ast.Expression{}
     if p.peekToken.Type == token.RPAREN {
         p.nextToken()
-        return identifiers
+        return true
     }
-    
-    p.nextToken()
 
-    ident := &ast.Indentifier{Token: p.curToken, Value: p.curToken.Literal}
-    identifiers = append(identifiers, ident)
+    seen := map[string]bool{}
+    for {
+        rest := p.peekToken.Type == token.ELLIPSIS
+        if rest {
+            p.nextToken()
+        }
+        if !p.expectPeek(token.IDENT) {
+            return false
+        }
 
-    for p.peekToken.Type == token.COMMA {
-        p.nextToken()
-        p.nextToken()
         ident := &ast.Indentifier{Token: p.curToken, Value: p.curToken.Literal}
-        identifiers = append(identifiers, ident)
-    }
-    if !p.expectPeek(token.RPAREN) {
-        return nil
+        if seen[ident.Value] {
+            p.addError(p.curToken, nil, fmt.Sprintf("duplicate parameter %s", ident.Value))
+            return false
+        }
+        seen[ident.Value] = true
+
+        if rest {
+            lit.Rest = ident
+            break
+        }
+
+        var value ast.Expression
+        if p.peekToken.Type == token.ASSIGN {
+            p.nextToken()
+            p.nextToken()
+            p.inDefault = true
+            value = p.parseExpression(LOWEST)
+            p.inDefault = false
+        } else if len(lit.Defaults) > 0 && lit.Defaults[len(lit.Defaults)-1] != nil {
+            p.addError(p.curToken, nil, fmt.Sprintf("parameter %s without a default follows a parameter with one", ident.Value))
+            return false
+        }
+
+        lit.Parameters = append(lit.Parameters, ident)
+        lit.Defaults = append(lit.Defaults, value)
+
+        if p.peekToken.Type != token.COMMA {
+            break
+        }
+        p.nextToken()
     }
 
-    return identifiers
+    return p.expectPeek(token.RPAREN)
 }
 
 func (p *Parser) parseCallExpression(function ast.Expression) ast.Expression {
     exp := &ast.CallExpression{Token: p.curToken, Function: function}
     exp.Arguments = p.parseCallArguments()
+    if p.curToken.Type == token.RPAREN {
+        exp.Rparen = p.curToken
+    }
 
     return exp
 }
 
+// parseCallArguments parses the arguments of a call, which are expressions,
+// spreads like ...list, and named arguments like name: value after them.
 func (p *Parser) parseCallArguments() []ast.Expression {
     args := []ast.Expression{}
-
     if p.peekToken.Type == token.RPAREN {
         p.nextToken()
         return args
     }
 
+    named := false
+    for {
+        p.nextToken()
+
+        isNamed := p.curToken.Type == token.IDENT && p.peekToken.Type == token.COLON
+        if named && !isNamed {
+            p.addError(p.curToken, nil, "positional argument follows named argument")
+            return nil
+        }
+        named = isNamed
+
+        switch {
+        case named:
+            name := &ast.Indentifier{Token: p.curToken, Value: p.curToken.Literal}
+            arg := &ast.NamedArgument{Token: p.curToken, Name: name}
+            p.nextToken()
+            p.nextToken()
+            arg.Value = p.parseExpression(LOWEST)
+            args = append(args, arg)
+        case p.curToken.Type == token.ELLIPSIS:
+            arg := &ast.SpreadExpression{Token: p.curToken}
+            p.nextToken()
+            arg.Value = p.parseExpression(LOWEST)
+            args = append(args, arg)
+        default:
+            args = append(args, p.parseExpression(LOWEST))
+        }
+
+        if p.peekToken.Type != token.COMMA {
+            break
+        }
+        p.nextToken()
+    }
+
+    if !p.expectPeek(token.RPAREN) {
+        return nil
+    }
+
+    return args
+}
+
+func (p *Parser) parseArrayLiteral() ast.Expression {
+    array := &ast.ArrayLiteral{Token: p.curToken}
+    array.Elements = p.parseExpressionList(token.RBRACKET)
+    if p.curToken.Type == token.RBRACKET {
+        array.Rbracket = p.curToken
+    }
+
+    return array
+}
+
+func (p *Parser) parseHashLiteral() ast.Expression {
+    hash := &ast.HashLiteral{Token: p.curToken}
+    hash.Keys = []ast.Expression{}
+    hash.Values = []ast.Expression{}
+
+    for p.peekToken.Type != token.RBRACE {
+        p.nextToken()
+        key := p.parseExpression(LOWEST)
+
+        if !p.expectPeek(token.COLON) {
+            return nil
+        }
+
+        p.nextToken()
+        value := p.parseExpression(LOWEST)
+
+        hash.Keys = append(hash.Keys, key)
+        hash.Values = append(hash.Values, value)
+
+        if p.peekToken.Type != token.RBRACE && !p.expectPeek(token.COMMA) {
+            return nil
+        }
+    }
+
+    if !p.expectPeek(token.RBRACE) {
+        return nil
+    }
+    hash.Rbrace = p.curToken
+
+    return hash
+}
+
+func (p *Parser) parseIndexExpression(left ast.Expression) ast.Expression {
+    exp := &ast.IndexExpression{Token: p.curToken, Left: left}
+
+    p.nextToken()
+    exp.Index = p.parseExpression(LOWEST)
+
+    if !p.expectPeek(token.RBRACKET) {
+        return nil
+    }
+    exp.Rbracket = p.curToken
+
+    return exp
+}
+
+func (p *Parser) parseExpressionList(end token.TokenType) []ast.Expression {
+    list := []ast.Expression{}
+
+    if p.peekToken.Type == end {
+        p.nextToken()
+        return list
+    }
+
     p.nextToken()
-    args = append(args, p.parseExpression(LOWEST))
+    list = append(list, p.parseExpression(LOWEST))
 
     for p.peekToken.Type == token.COMMA {
         p.nextToken()
         p.nextToken()
-        args = append(args, p.parseExpression(LOWEST))
+        list = append(list, p.parseExpression(LOWEST))
     }
 
-    if !p.expectPeek(token.RPAREN) {
+    if !p.expectPeek(end) {
         return nil
     }
 
-    return args
+    return list
 }
 
 func (p *Parser) parseStringLiteral() ast.Expression {
@@ -380,8 +850,20 @@ func (p *Parser) peekError(t token.TokenType) {
         t,
         p.peekToken.Type,
     ) 
+    if p.peekToken.Type == token.ILLEGAL {
+        msg = illegalTokenMessage(p.peekToken)
+    }
 
-    p.errors = append(p.errors, msg)
+    p.addError(p.peekToken, []token.TokenType{t}, msg)
+}
+
+func (p *Parser) addError(actual token.Token, expected []token.TokenType, msg string) {
+    p.errors = append(p.errors, &ParseError{
+        Pos: actual.Pos,
+        Expected: expected,
+        Actual: actual,
+        Message: msg,
+    })
 }
 
 func (p *Parser) registerPrefix(tokenType token.TokenType, fn prefixParserFn) {
@@ -394,7 +876,20 @@ func (p *Parser) registerInfix(tokenType token.TokenType, fn infixParserFn) {
 
 func (p *Parser) noPrefixParseFnError(t token.TokenType) {
     msg := fmt.Sprintf("No prefix parse function for %s found", t)
-    p.errors = append(p.errors, msg)
+    if t == token.ILLEGAL {
+        msg = illegalTokenMessage(p.curToken)
+    }
+    p.addError(p.curToken, nil, msg)
+}
+
+// illegalTokenMessage describes an ILLEGAL token. Lexical errors, like an
+// unterminated block comment, carry their message as the token literal.
+func illegalTokenMessage(tok token.Token) string {
+    if utf8.RuneCountInString(tok.Literal) > 1 {
+        return tok.Literal
+    }
+
+    return fmt.Sprintf("illegal character %q", tok.Literal)
 }
 
 func (p *Parser) peekPrecedence() int {
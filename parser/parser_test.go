@@ -4,6 +4,7 @@ import (
 	"fmt"
 	"interpreter/ast"
 	"interpreter/lexer"
+	"interpreter/token"
 	"testing"
 )
 
@@ -165,6 +166,53 @@ func TestIntegerLiteralExpression(t *testing.T) {
     }
 }
 
+func TestBigIntegerLiteralExpression(t *testing.T) {
+    input := "123456789012345678901234567890;"
+
+    l := lexer.New(input)
+    p := New(l)
+    program := p.ParseProgram()
+    checkParserErrors(t, p)
+
+    stmt := program.Statements[0].(*ast.ExpressionStatement)
+    literal, ok := stmt.Expression.(*ast.IntegerLiteral)
+    if !ok {
+        t.Fatalf("expression not *ast.IntegerLiteral. got=%T", stmt.Expression)
+    }
+
+    if literal.Big == nil || literal.Big.String() != "123456789012345678901234567890" {
+        t.Errorf("literal.Big wrong. got=%v", literal.Big)
+    }
+}
+
+func TestFloatLiteralExpression(t *testing.T) {
+    tests := []struct {
+        input string
+        expected float64
+    }{
+        {"1.5;", 1.5},
+        {"2e3;", 2000},
+        {"1e-3;", 0.001},
+    }
+
+    for _, tt := range tests {
+        l := lexer.New(tt.input)
+        p := New(l)
+        program := p.ParseProgram()
+        checkParserErrors(t, p)
+
+        stmt := program.Statements[0].(*ast.ExpressionStatement)
+        literal, ok := stmt.Expression.(*ast.FloatLiteral)
+        if !ok {
+            t.Fatalf("expression not *ast.FloatLiteral. got=%T", stmt.Expression)
+        }
+
+        if literal.Value != tt.expected {
+            t.Errorf("literal.Value not %g. got=%g", tt.expected, literal.Value)
+        }
+    }
+}
+
 func TestBooleanExpression(t *testing.T) {
     input := "true"
 
@@ -337,6 +385,23 @@ func TestOperatorPrecedenceParsing(t *testing.T) {
         {"true", "true"},
         {"false", "false"},
         {"3 > 5 == false", "((3 > 5) == false)"},
+        {"a <= b == b >= a", "((a <= b) == (b >= a))"},
+        {"a && b || c && d", "((a && b) || (c && d))"},
+        {"a || b && c", "(a || (b && c))"},
+        {"a == 1 && b < 2 + 3", "((a == 1) && (b < (2 + 3)))"},
+        {"!a && b", "((!a) && b)"},
+        {"a * b % c", "((a * b) % c)"},
+        {"2 ** 3 ** 2", "(2 ** (3 ** 2))"},
+        {"-2 ** 2", "(-(2 ** 2))"},
+        {"2 ** -1", "(2 ** (-1))"},
+        {"a * b ** c", "(a * (b ** c))"},
+        {"f(x) ** 2", "(f(x) ** 2)"},
+        {"a | b ^ c & d", "(a | (b ^ (c & d)))"},
+        {"a & b == c", "((a & b) == c)"},
+        {"a << 1 + b", "(a << (1 + b))"},
+        {"a & b << c", "(a & (b << c))"},
+        {"~a & b", "((~a) & b)"},
+        {"a || b | c", "(a || (b | c))"},
         {"3 < 5 == true", "((3 < 5) == true)"},
         {
             "1 + (2 + 3) + 4",
@@ -370,6 +435,14 @@ func TestOperatorPrecedenceParsing(t *testing.T) {
             "add(a + b + c * d / f + g)",
             "add((((a + b) + ((c * d) / f)) + g))",
         },
+        {
+            "a * [1, 2, 3, 4][b * c] * d",
+            "((a * ([1, 2, 3, 4][(b * c)])) * d)",
+        },
+        {
+            "add(a * b[2], b[1], 2 * [1, 2][1])",
+            "add((a * (b[2])), (b[1]), (2 * ([1, 2][1])))",
+        },
     }
 
     for _, tt := range tests {
@@ -511,6 +584,54 @@ func TestCallExpressionParsing(t *testing.T) {
     testInfixExpression(t, exp.Arguments[2], 4, "+", 5)
 }
 
+func TestFunctionArgumentsParsing(t *testing.T) {
+    tests := []struct {
+        input string
+        expected string
+    }{
+        {"fn(a, b = 10, ...rest) { a }", "fn(a, b = 10, ...rest) a"},
+        {"fn(...args) { args }", "fn(...args) args"},
+        {"fn(a = 1, b = a * 2) { b }", "fn(a = 1, b = (a * 2)) b"},
+        {"f(...xs)", "f(...xs)"},
+        {"f(1, ...xs, b: 2 + 3)", "f(1, ...xs, b: (2 + 3))"},
+        {"f(a: 1, b: 2)", "f(a: 1, b: 2)"},
+    }
+
+    for _, tt := range tests {
+        p := New(lexer.New(tt.input))
+        program := p.ParseProgram()
+        checkParserErrors(t, p)
+
+        if program.String() != tt.expected {
+            t.Errorf("expected=%q, got=%q", tt.expected, program.String())
+        }
+    }
+}
+
+func TestFunctionArgumentErrors(t *testing.T) {
+    tests := []struct {
+        input string
+        expectedError string
+    }{
+        {"fn(a, a) { a }", "1:7: duplicate parameter a"},
+        {"fn(a = 1, b) { a }", "1:11: parameter b without a default follows a parameter with one"},
+        {"fn(...a, b) { a }", "1:8: expected next token to be ) got , instead"},
+        {"f(a: 1, 2)", "1:9: positional argument follows named argument"},
+        {"while (x) { fn(a = if (x) { break } else { 1 }) { a } }", "1:29: break outside of a loop"},
+        {"fn(a = if (x) { return 1 } else { 1 }) { a }", "1:17: return in a default parameter value"},
+    }
+
+    for _, tt := range tests {
+        p := New(lexer.New(tt.input))
+        p.ParseProgram()
+
+        errors := p.Errors()
+        if len(errors) != 1 || errors[0].Error() != tt.expectedError {
+            t.Errorf("%q: expected error %q, got %v", tt.input, tt.expectedError, errors)
+        }
+    }
+}
+
 func TestStringLiteral(t *testing.T) {
     input := `"hello world"`
     l := lexer.New(input)
@@ -529,6 +650,259 @@ func TestStringLiteral(t *testing.T) {
     }
 }
 
+func TestParsingArrayLiterals(t *testing.T) {
+    input := "[1, 2 * 2, 3 + 3]"
+
+    l := lexer.New(input)
+    p := New(l)
+    program := p.ParseProgram()
+    checkParserErrors(t, p)
+
+    stmt, ok := program.Statements[0].(*ast.ExpressionStatement)
+    if !ok {
+        t.Fatalf("stmt is not ast.ExpressionStatement. got=%T", program.Statements[0])
+    }
+
+    array, ok := stmt.Expression.(*ast.ArrayLiteral)
+    if !ok {
+        t.Fatalf("exp not ast.ArrayLiteral. got=%T", stmt.Expression)
+    }
+
+    if len(array.Elements) != 3 {
+        t.Fatalf("len(array.Elements) not 3. got=%d", len(array.Elements))
+    }
+
+    testIntegerLiteral(t, array.Elements[0], 1)
+    testInfixExpression(t, array.Elements[1], 2, "*", 2)
+    testInfixExpression(t, array.Elements[2], 3, "+", 3)
+}
+
+func TestParsingEmptyArrayLiteral(t *testing.T) {
+    input := "[]"
+
+    l := lexer.New(input)
+    p := New(l)
+    program := p.ParseProgram()
+    checkParserErrors(t, p)
+
+    stmt := program.Statements[0].(*ast.ExpressionStatement)
+    array, ok := stmt.Expression.(*ast.ArrayLiteral)
+    if !ok {
+        t.Fatalf("exp not ast.ArrayLiteral. got=%T", stmt.Expression)
+    }
+
+    if len(array.Elements) != 0 {
+        t.Errorf("len(array.Elements) not 0. got=%d", len(array.Elements))
+    }
+}
+
+func TestParsingIndexExpressions(t *testing.T) {
+    input := "myArray[1 + 1]"
+
+    l := lexer.New(input)
+    p := New(l)
+    program := p.ParseProgram()
+    checkParserErrors(t, p)
+
+    stmt, ok := program.Statements[0].(*ast.ExpressionStatement)
+    if !ok {
+        t.Fatalf("stmt is not ast.ExpressionStatement. got=%T", program.Statements[0])
+    }
+
+    indexExp, ok := stmt.Expression.(*ast.IndexExpression)
+    if !ok {
+        t.Fatalf("exp not *ast.IndexExpression. got=%T", stmt.Expression)
+    }
+
+    if !testIdentifier(t, indexExp.Left, "myArray") {
+        return
+    }
+
+    if !testInfixExpression(t, indexExp.Index, 1, "+", 1) {
+        return
+    }
+}
+
+func TestParsingHashLiterals(t *testing.T) {
+    input := `{"one": 1, "two": 2, "three": 3}`
+
+    l := lexer.New(input)
+    p := New(l)
+    program := p.ParseProgram()
+    checkParserErrors(t, p)
+
+    stmt := program.Statements[0].(*ast.ExpressionStatement)
+    hash, ok := stmt.Expression.(*ast.HashLiteral)
+    if !ok {
+        t.Fatalf("exp is not ast.HashLiteral. got=%T", stmt.Expression)
+    }
+
+    expectedKeys := []string{"one", "two", "three"}
+    expectedValues := []int64{1, 2, 3}
+
+    if len(hash.Keys) != len(expectedKeys) {
+        t.Fatalf("hash.Keys has wrong length. got=%d", len(hash.Keys))
+    }
+
+    for i, key := range hash.Keys {
+        literal, ok := key.(*ast.StringLiteral)
+        if !ok {
+            t.Errorf("key is not ast.StringLiteral. got=%T", key)
+            continue
+        }
+
+        if literal.Value != expectedKeys[i] {
+            t.Errorf("key[%d] expected %q, got %q", i, expectedKeys[i], literal.Value)
+        }
+
+        testIntegerLiteral(t, hash.Values[i], expectedValues[i])
+    }
+}
+
+func TestParsingEmptyHashLiteral(t *testing.T) {
+    input := "{}"
+
+    l := lexer.New(input)
+    p := New(l)
+    program := p.ParseProgram()
+    checkParserErrors(t, p)
+
+    stmt := program.Statements[0].(*ast.ExpressionStatement)
+    hash, ok := stmt.Expression.(*ast.HashLiteral)
+    if !ok {
+        t.Fatalf("exp is not ast.HashLiteral. got=%T", stmt.Expression)
+    }
+
+    if len(hash.Keys) != 0 {
+        t.Errorf("hash.Keys has wrong length. got=%d", len(hash.Keys))
+    }
+}
+
+func TestParsingHashLiteralsWithExpressions(t *testing.T) {
+    input := `{"one": 0 + 1, "two": 10 - 8, "three": 15 / 5}`
+
+    l := lexer.New(input)
+    p := New(l)
+    program := p.ParseProgram()
+    checkParserErrors(t, p)
+
+    stmt := program.Statements[0].(*ast.ExpressionStatement)
+    hash, ok := stmt.Expression.(*ast.HashLiteral)
+    if !ok {
+        t.Fatalf("exp is not ast.HashLiteral. got=%T", stmt.Expression)
+    }
+
+    if len(hash.Keys) != 3 {
+        t.Fatalf("hash.Keys has wrong length. got=%d", len(hash.Keys))
+    }
+
+    testInfixExpression(t, hash.Values[0], 0, "+", 1)
+    testInfixExpression(t, hash.Values[1], 10, "-", 8)
+    testInfixExpression(t, hash.Values[2], 15, "/", 5)
+
+    if hash.String() != "{one: (0 + 1), two: (10 - 8), three: (15 / 5)}" {
+        t.Errorf("hash.String() wrong. got=%q", hash.String())
+    }
+}
+
+func TestNodePositions(t *testing.T) {
+    input := `let add = fn(x, y) {
+    x + y;
+};
+add(1, 2 * 3)`
+
+    l := lexer.New(input)
+    p := New(l)
+    program := p.ParseProgram()
+    checkParserErrors(t, p)
+
+    tests := []struct {
+        node ast.Node
+        expectedStart string
+        expectedEnd string
+    }{
+        {program, "1:1", "4:14"},
+        {program.Statements[0], "1:1", "3:2"},
+        {program.Statements[0].(*ast.LetStatemet).Value, "1:11", "3:2"},
+        {program.Statements[1], "4:1", "4:14"},
+        {program.Statements[1].(*ast.ExpressionStatement).Expression.(*ast.CallExpression).Arguments[1], "4:8", "4:13"},
+    }
+
+    for i, tt := range tests {
+        if tt.node.Pos().String() != tt.expectedStart {
+            t.Errorf("tests[%d] (%s) expected start %s, got %s", i, tt.node, tt.expectedStart, tt.node.Pos())
+        }
+
+        if tt.node.End().String() != tt.expectedEnd {
+            t.Errorf("tests[%d] (%s) expected end %s, got %s", i, tt.node, tt.expectedEnd, tt.node.End())
+        }
+    }
+}
+
+func TestParseErrors(t *testing.T) {
+    input := `let x 5;
+let y = 10;
+let = 3;
+fn(a) { let b = ; b };
+if (y { y }
+let z = add(1, 2;
+y`
+
+    l := lexer.New(input)
+    p := New(l)
+    program := p.ParseProgram()
+
+    expectedErrors := []struct {
+        pos string
+        expected []token.TokenType
+        actual token.TokenType
+    }{
+        {"1:7", []token.TokenType{token.ASSIGN}, token.INT},
+        {"3:5", []token.TokenType{token.IDENT}, token.ASSIGN},
+        {"4:17", nil, token.SEMICOLON},
+        {"5:7", []token.TokenType{token.RPAREN}, token.LBRACE},
+        {"6:17", []token.TokenType{token.RPAREN}, token.SEMICOLON},
+    }
+
+    errors := p.Errors()
+    if len(errors) != len(expectedErrors) {
+        for _, err := range errors {
+            t.Logf("parser error %q", err)
+        }
+        t.Fatalf("expected %d errors, got %d", len(expectedErrors), len(errors))
+    }
+
+    for i, tt := range expectedErrors {
+        err := errors[i]
+        if err.Pos.String() != tt.pos {
+            t.Errorf("errors[%d] expected pos %s, got %s", i, tt.pos, err.Pos)
+        }
+
+        if fmt.Sprint(err.Expected) != fmt.Sprint(tt.expected) {
+            t.Errorf("errors[%d] expected token set %v, got %v", i, tt.expected, err.Expected)
+        }
+
+        if err.Actual.Type != tt.actual {
+            t.Errorf("errors[%d] expected actual token %s, got %s", i, tt.actual, err.Actual.Type)
+        }
+    }
+
+    expectedStatements := []string{"let y = 10;", "y"}
+    if len(program.Statements) != len(expectedStatements) {
+        t.Fatalf("expected %d statements, got %d (%q)", len(expectedStatements), len(program.Statements), program)
+    }
+
+    for i, expected := range expectedStatements {
+        if program.Statements[i] == nil {
+            t.Fatalf("program.Statements[%d] is nil", i)
+        }
+
+        if program.Statements[i].String() != expected {
+            t.Errorf("program.Statements[%d] expected %q, got %q", i, expected, program.Statements[i])
+        }
+    }
+}
+
 func testIntegerLiteral(t *testing.T, il ast.Expression, value int64) bool {
     integer, ok := il.(*ast.IntegerLiteral)
     if !ok {
@@ -651,3 +1025,196 @@ func checkParserErrors(t *testing.T, p *Parser) {
 
     t.FailNow()
 }
+
+func TestIllegalTokenErrors(t *testing.T) {
+    tests := []struct {
+        input string
+        expectedError string
+    }{
+        {"let x = 1; /* never closed", "1:12: unterminated block comment"},
+        {"let x /* never closed", "1:7: unterminated block comment"},
+        {"let x = @;", "1:9: illegal character \"@\""},
+        {"let x = 1 € 2;", "1:11: illegal character \"€\""},
+        {`let s = "open;`, "1:9: unterminated string literal"},
+        {`puts("\x");`, "1:6: invalid escape sequence \\x in string literal"},
+    }
+
+    for _, tt := range tests {
+        p := New(lexer.New(tt.input))
+        p.ParseProgram()
+
+        errors := p.Errors()
+        if len(errors) != 1 {
+            t.Errorf("%q: expected 1 error, got %v", tt.input, errors)
+            continue
+        }
+
+        if errors[0].Error() != tt.expectedError {
+            t.Errorf("%q: expected error %q, got %q", tt.input, tt.expectedError, errors[0].Error())
+        }
+    }
+}
+
+func TestAssignExpressions(t *testing.T) {
+    tests := []struct {
+        input string
+        expected string
+    }{
+        {"x = 5", "x = 5"},
+        {"x = y = 1 + 2", "x = y = (1 + 2)"},
+        {"x += 1 * 2", "x += (1 * 2)"},
+        {"x -= 1; y *= 2; z /= 3", "x -= 1y *= 2z /= 3"},
+        {"a[0] += 1", "(a[0]) += 1"},
+        {"h[\"k\"] = fn(x) { x }", "(h[k]) = fn(x) x"},
+    }
+
+    for _, tt := range tests {
+        p := New(lexer.New(tt.input))
+        program := p.ParseProgram()
+        checkParserErrors(t, p)
+
+        if actual := program.String(); actual != tt.expected {
+            t.Errorf("expected=%q, got=%q", tt.expected, actual)
+        }
+    }
+
+    p := New(lexer.New("a = 1"))
+    program := p.ParseProgram()
+    checkParserErrors(t, p)
+
+    stmt := program.Statements[0].(*ast.ExpressionStatement)
+    assign, ok := stmt.Expression.(*ast.AssignExpression)
+    if !ok {
+        t.Fatalf("exp not *ast.AssignExpression. got=%T", stmt.Expression)
+    }
+    if assign.Operator != "=" {
+        t.Errorf("assign.Operator is not '='. got=%q", assign.Operator)
+    }
+    testIdentifier(t, assign.Target, "a")
+    testIntegerLiteral(t, assign.Value, 1)
+}
+
+func TestInvalidAssignmentTargets(t *testing.T) {
+    tests := []struct {
+        input string
+        expectedError string
+    }{
+        {"5 = 1", "1:3: cannot assign to 5"},
+        {"f() = 1", "1:5: cannot assign to f()"},
+        {"x + y = 1", "1:7: cannot assign to (x + y)"},
+    }
+
+    for _, tt := range tests {
+        p := New(lexer.New(tt.input))
+        p.ParseProgram()
+
+        errors := p.Errors()
+        if len(errors) != 1 || errors[0].Error() != tt.expectedError {
+            t.Errorf("%q: expected error %q, got %v", tt.input, tt.expectedError, errors)
+        }
+    }
+}
+
+func TestWhileStatement(t *testing.T) {
+    input := `while (x < y) { x += 1; break; }`
+    p := New(lexer.New(input))
+    program := p.ParseProgram()
+    checkParserErrors(t, p)
+
+    if len(program.Statements) != 1 {
+        t.Fatalf("program.Statements does not contain 1 statement. got=%d", len(program.Statements))
+    }
+
+    stmt, ok := program.Statements[0].(*ast.WhileStatement)
+    if !ok {
+        t.Fatalf("statement is not ast.WhileStatement. got=%T", program.Statements[0])
+    }
+
+    if !testInfixExpression(t, stmt.Condition, "x", "<", "y") {
+        return
+    }
+
+    if len(stmt.Body.Statements) != 2 {
+        t.Fatalf("body is not 2 statements. got=%d", len(stmt.Body.Statements))
+    }
+    if _, ok := stmt.Body.Statements[1].(*ast.BreakStatement); !ok {
+        t.Errorf("Statements[1] is not ast.BreakStatement. got=%T", stmt.Body.Statements[1])
+    }
+}
+
+func TestForStatement(t *testing.T) {
+    tests := []struct {
+        input string
+        expected string
+    }{
+        {"for (let i = 0; i < 10; i += 1) { continue; }", "for (let i = 0; (i < 10); i += 1) continue;"},
+        {"for (i = 0; i < 10; i += 1) { x }", "for (i = 0; (i < 10); i += 1) x"},
+        {"for (;;) { break }", "for (; ; ) break;"},
+        {"for (; ok;) { x }; y", "for (; ok; ) xy"},
+        {"while (true) { fn() { 1 }; break }", "whiletrue fn() 1break;"},
+        {"for (x in xs) { x }", "for (x in xs) x"},
+        {"for (k, v in f(h)) { k + v }", "for (k, v in f(h)) (k + v)"},
+        {"for (c in \"abc\") { continue }; c", "for (c in abc) continue;c"},
+    }
+
+    for _, tt := range tests {
+        p := New(lexer.New(tt.input))
+        program := p.ParseProgram()
+        checkParserErrors(t, p)
+
+        if actual := program.String(); actual != tt.expected {
+            t.Errorf("expected=%q, got=%q", tt.expected, actual)
+        }
+    }
+}
+
+func TestLoopErrors(t *testing.T) {
+    tests := []struct {
+        input string
+        expectedError string
+    }{
+        {"break;", "1:1: break outside of a loop"},
+        {"if (x) { continue }", "1:10: continue outside of a loop"},
+        {"while (x) { fn() { break } }", "1:20: break outside of a loop"},
+        {"while x { }", "1:7: expected next token to be ( got IDENT instead"},
+        {"for (let i = 0; i < 1; i += 1 { }", "1:31: expected next token to be ) got { instead"},
+        {"for (let i = 0; i < 1) { }", "1:22: expected next token to be ; got ) instead"},
+        {"for (k, in xs) { }", "1:9: expected next token to be IDENT got IN instead"},
+        {"for (x in xs { }", "1:14: expected next token to be ) got { instead"},
+    }
+
+    for _, tt := range tests {
+        p := New(lexer.New(tt.input))
+        p.ParseProgram()
+
+        errors := p.Errors()
+        if len(errors) != 1 || errors[0].Error() != tt.expectedError {
+            t.Errorf("%q: expected error %q, got %v", tt.input, tt.expectedError, errors)
+        }
+    }
+}
+
+func TestForInStatement(t *testing.T) {
+    p := New(lexer.New("for (k, v in h) { v }"))
+    program := p.ParseProgram()
+    checkParserErrors(t, p)
+
+    stmt, ok := program.Statements[0].(*ast.ForInStatement)
+    if !ok {
+        t.Fatalf("statement is not ast.ForInStatement. got=%T", program.Statements[0])
+    }
+
+    testIdentifier(t, stmt.Key, "k")
+    testIdentifier(t, stmt.Value, "v")
+    testIdentifier(t, stmt.Iterable, "h")
+
+    p = New(lexer.New("for (x in [1, 2]) { x }"))
+    program = p.ParseProgram()
+    checkParserErrors(t, p)
+
+    stmt = program.Statements[0].(*ast.ForInStatement)
+    if stmt.Key != nil {
+        t.Errorf("stmt.Key is not nil. got=%+v", stmt.Key)
+    }
+    testIdentifier(t, stmt.Value, "x")
+}
@@ -0,0 +1,259 @@
+package repl
+
+import (
+	"bufio"
+	"errors"
+	"io"
+	"os"
+	"strconv"
+	"strings"
+	"unicode"
+)
+
+// errInterrupted is returned by ReadLine when the user presses Ctrl-C.
+var errInterrupted = errors.New("interrupted")
+
+type lineReader interface {
+    ReadLine(prompt string) (string, error)
+    Close() error
+}
+
+// scannerReader reads lines from a non-interactive input such as a pipe.
+type scannerReader struct {
+    scanner *bufio.Scanner
+    out io.Writer
+}
+
+func (r *scannerReader) ReadLine(prompt string) (string, error) {
+    io.WriteString(r.out, prompt)
+
+    if !r.scanner.Scan() {
+        if err := r.scanner.Err(); err != nil {
+            return "", err
+        }
+        return "", io.EOF
+    }
+
+    return r.scanner.Text(), nil
+}
+
+func (r *scannerReader) Close() error {
+    return nil
+}
+
+// editor reads lines from a terminal with cursor movement, history
+// navigation and a persistent history file.
+type editor struct {
+    in *os.File
+    reader *bufio.Reader
+    out io.Writer
+    history *history
+
+    prompt string
+    buf []rune
+    cursor int
+    historyIdx int
+    pending []rune //line being edited before browsing the history
+}
+
+func newEditor(in *os.File, out io.Writer, history *history) (*editor, error) {
+    if _, err := getTerminalState(in.Fd()); err != nil {
+        return nil, err
+    }
+
+    return &editor{
+        in: in,
+        reader: bufio.NewReader(in),
+        out: out,
+        history: history,
+    }, nil
+}
+
+func (e *editor) ReadLine(prompt string) (string, error) {
+    state, err := makeRaw(e.in.Fd())
+    if err != nil {
+        return "", err
+    }
+    defer setTerminalState(e.in.Fd(), state)
+
+    e.prompt = prompt
+    e.buf = e.buf[:0]
+    e.cursor = 0
+    e.historyIdx = len(e.history.entries)
+    e.pending = nil
+    e.refresh()
+
+    for {
+        r, _, err := e.reader.ReadRune()
+        if err != nil {
+            return "", err
+        }
+
+        switch r {
+        case '\r', '\n':
+            io.WriteString(e.out, "\n")
+            line := string(e.buf)
+            e.history.Add(line)
+            return line, nil
+        case 3: //Ctrl-C
+            io.WriteString(e.out, "^C\n")
+            return "", errInterrupted
+        case 4: //Ctrl-D
+            if len(e.buf) == 0 {
+                io.WriteString(e.out, "\n")
+                return "", io.EOF
+            }
+            e.deleteAt(e.cursor)
+        case 127, 8: //Backspace, Ctrl-H
+            if e.cursor > 0 {
+                e.cursor--
+                e.deleteAt(e.cursor)
+            }
+        case 1: //Ctrl-A
+            e.cursor = 0
+        case 5: //Ctrl-E
+            e.cursor = len(e.buf)
+        case 2: //Ctrl-B
+            e.moveCursor(-1)
+        case 6: //Ctrl-F
+            e.moveCursor(1)
+        case 16: //Ctrl-P
+            e.browseHistory(-1)
+        case 14: //Ctrl-N
+            e.browseHistory(1)
+        case 11: //Ctrl-K
+            e.buf = e.buf[:e.cursor]
+        case 21: //Ctrl-U
+            e.buf = append(e.buf[:0], e.buf[e.cursor:]...)
+            e.cursor = 0
+        case 27: //ESC
+            e.readEscapeSequence()
+        default:
+            if unicode.IsPrint(r) || r == '\t' {
+                e.insert(r)
+            }
+        }
+        e.refresh()
+    }
+}
+
+// readEscapeSequence handles the ANSI sequences sent by arrow, Home, End
+// and Delete keys.
+func (e *editor) readEscapeSequence() {
+    r, _, err := e.reader.ReadRune()
+    if err != nil || (r != '[' && r != 'O') {
+        return
+    }
+
+    r, _, err = e.reader.ReadRune()
+    if err != nil {
+        return
+    }
+
+    switch r {
+    case 'A':
+        e.browseHistory(-1)
+    case 'B':
+        e.browseHistory(1)
+    case 'C':
+        e.moveCursor(1)
+    case 'D':
+        e.moveCursor(-1)
+    case 'H':
+        e.cursor = 0
+    case 'F':
+        e.cursor = len(e.buf)
+    default:
+        if r < '0' || r > '9' {
+            return
+        }
+
+        code := string(r)
+        for {
+            r, _, err = e.reader.ReadRune()
+            if err != nil {
+                return
+            }
+            if (r < '0' || r > '9') && r != ';' {
+                break
+            }
+            code += string(r)
+        }
+        if r != '~' {
+            return
+        }
+
+        switch code {
+        case "1", "7":
+            e.cursor = 0
+        case "4", "8":
+            e.cursor = len(e.buf)
+        case "3":
+            e.deleteAt(e.cursor)
+        }
+    }
+}
+
+func (e *editor) insert(r rune) {
+    e.buf = append(e.buf, 0)
+    copy(e.buf[e.cursor+1:], e.buf[e.cursor:])
+    e.buf[e.cursor] = r
+    e.cursor++
+}
+
+func (e *editor) deleteAt(idx int) {
+    if idx < 0 || idx >= len(e.buf) {
+        return
+    }
+
+    e.buf = append(e.buf[:idx], e.buf[idx+1:]...)
+}
+
+func (e *editor) moveCursor(delta int) {
+    cursor := e.cursor + delta
+    if cursor >= 0 && cursor <= len(e.buf) {
+        e.cursor = cursor
+    }
+}
+
+func (e *editor) browseHistory(delta int) {
+    idx := e.historyIdx + delta
+    if idx < 0 || idx > len(e.history.entries) {
+        return
+    }
+
+    if e.historyIdx == len(e.history.entries) {
+        e.pending = append([]rune{}, e.buf...)
+    }
+    e.historyIdx = idx
+
+    if idx == len(e.history.entries) {
+        e.buf = append(e.buf[:0], e.pending...)
+    } else {
+        e.buf = append(e.buf[:0], []rune(e.history.entries[idx])...)
+    }
+    e.cursor = len(e.buf)
+}
+
+// refresh redraws the prompt and the line, then moves the terminal cursor
+// back to the editing position.
+func (e *editor) refresh() {
+    var out strings.Builder
+
+    out.WriteString("\r")
+    out.WriteString(e.prompt)
+    out.WriteString(string(e.buf))
+    out.WriteString("\x1b[K")
+
+    if back := len(e.buf) - e.cursor; back > 0 {
+        out.WriteString("\x1b[")
+        out.WriteString(strconv.Itoa(back))
+        out.WriteString("D")
+    }
+
+    io.WriteString(e.out, out.String())
+}
+
+func (e *editor) Close() error {
+    return e.history.Close()
+}
@@ -0,0 +1,80 @@
+package repl
+
+import (
+	"bufio"
+	"os"
+	"path/filepath"
+	"strings"
+)
+
+const (
+    HISTORY_FILE = ".interpreter_history"
+    MAX_HISTORY = 1000
+)
+
+// history keeps the lines entered in the REPL and appends every new line
+// to a dotfile so it survives between sessions.
+type history struct {
+    entries []string
+    file *os.File
+}
+
+// historyPath returns the location of the history dotfile, or "" if the
+// home directory is unknown.
+func historyPath() string {
+    home, err := os.UserHomeDir()
+    if err != nil {
+        return ""
+    }
+
+    return filepath.Join(home, HISTORY_FILE)
+}
+
+// loadHistory reads previous entries from path and opens it for appending.
+// History still works in memory if the file cannot be used.
+func loadHistory(path string) *history {
+    h := &history{}
+    if path == "" {
+        return h
+    }
+
+    if f, err := os.Open(path); err == nil {
+        scanner := bufio.NewScanner(f)
+        for scanner.Scan() {
+            h.entries = append(h.entries, scanner.Text())
+        }
+        f.Close()
+
+        if len(h.entries) > MAX_HISTORY {
+            h.entries = h.entries[len(h.entries)-MAX_HISTORY:]
+        }
+    }
+
+    if f, err := os.OpenFile(path, os.O_WRONLY|os.O_APPEND|os.O_CREATE, 0600); err == nil {
+        h.file = f
+    }
+
+    return h
+}
+
+func (h *history) Add(line string) {
+    if strings.TrimSpace(line) == "" {
+        return
+    }
+    if len(h.entries) > 0 && h.entries[len(h.entries)-1] == line {
+        return
+    }
+
+    h.entries = append(h.entries, line)
+    if h.file != nil {
+        h.file.WriteString(line + "\n")
+    }
+}
+
+func (h *history) Close() error {
+    if h.file == nil {
+        return nil
+    }
+
+    return h.file.Close()
+}
@@ -2,46 +2,159 @@ package repl
 
 import (
 	"bufio"
-	"fmt"
 	"interpreter/evaluator"
 	"interpreter/lexer"
 	"interpreter/object"
 	"interpreter/parser"
+	"interpreter/token"
 	"io"
+	"os"
+	"strings"
 )
 
-const PROMPT = ">> "
+const (
+    PROMPT = ">> "
+    CONTINUATION_PROMPT = ".. "
+)
+
+func Start(in io.Reader, options object.Options) {
+    out := options.Out()
+    reader := newLineReader(in, out)
+    defer reader.Close()
 
-func Start(in io.Reader, out io.Writer) {
-    scanner := bufio.NewScanner(in)
-    env := object.NewEnviroment()
+    env := object.NewEnviromentWithOptions(options)
+    var input strings.Builder
 
     for {
-        fmt.Printf(PROMPT)
-        
-        if !scanner.Scan() {
+        prompt := PROMPT
+        if input.Len() > 0 {
+            prompt = CONTINUATION_PROMPT
+        }
+
+        line, err := reader.ReadLine(prompt)
+        if err == errInterrupted {
+            input.Reset()
+            continue
+        }
+        if err != nil {
+            if input.Len() > 0 {
+                evaluate(input.String(), env, out)
+            }
             return
         }
 
-        line := scanner.Text()
-        l := lexer.New(line)
-        p := parser.New(l)
-        program := p.ParseProgram()
+        input.WriteString(line)
+        input.WriteString("\n")
 
-        if len(p.Errors()) != 0 {
-            printParserErrors(out, p.Errors())
+        if isIncomplete(input.String()) {
+            continue
         }
 
-        evaluated := evaluator.Eval(program, env)
-        if evaluated != nil {
-            io.WriteString(out, string(evaluated.Inspect()))
-            io.WriteString(out, "\n")
+        evaluate(input.String(), env, out)
+        input.Reset()
+    }
+}
+
+func newLineReader(in io.Reader, out io.Writer) lineReader {
+    if f, ok := in.(*os.File); ok {
+        if e, err := newEditor(f, out, loadHistory(historyPath())); err == nil {
+            return e
         }
     }
+
+    return &scannerReader{scanner: bufio.NewScanner(in), out: out}
+}
+
+func evaluate(input string, env *object.Enviroment, out io.Writer) {
+    l := lexer.New(input)
+    p := parser.New(l)
+    program := p.ParseProgram()
+
+    if len(p.Errors()) != 0 {
+        printParserErrors(out, p.Errors())
+        return
+    }
+
+    evaluated := evaluator.Eval(program, env)
+    if evaluated != nil {
+        io.WriteString(out, string(evaluated.Inspect()))
+        io.WriteString(out, "\n")
+    }
+}
+
+// continuationTokens are the tokens after which a line break can't end the
+// input, since something has to follow them.
+var continuationTokens = map[token.TokenType]bool{
+    token.ASSIGN: true,
+    token.PLUS_ASSIGN: true,
+    token.MINUS_ASSIGN: true,
+    token.ASTERISK_ASSIGN: true,
+    token.SLASH_ASSIGN: true,
+    token.PLUS: true,
+    token.MINUS: true,
+    token.BANG: true,
+    token.ASTERISK: true,
+    token.SLASH: true,
+    token.PERCENT: true,
+    token.POWER: true,
+    token.BIT_AND: true,
+    token.BIT_OR: true,
+    token.BIT_XOR: true,
+    token.BIT_NOT: true,
+    token.SHL: true,
+    token.SHR: true,
+    token.LT: true,
+    token.GT: true,
+    token.EQ: true,
+    token.NOT_EQ: true,
+    token.LT_EQ: true,
+    token.GT_EQ: true,
+    token.AND: true,
+    token.OR: true,
+    token.COMMA: true,
+}
+
+// isIncomplete reports whether input stops inside brackets, a block comment
+// or a string, or right after an operator, `=` or `,` where the parser runs
+// into the end of the input, so the REPL should keep reading lines. Other
+// input is evaluated right away, and reports its parse errors if it is cut
+// short.
+func isIncomplete(input string) bool {
+    l := lexer.New(input)
+    depth := 0
+    var last token.Token
+
+    for tok := l.NextToken(); tok.Type != token.EOF; tok = l.NextToken() {
+        switch tok.Type {
+        case token.LPAREN, token.LBRACE, token.LBRACKET:
+            depth++
+        case token.RPAREN, token.RBRACE, token.RBRACKET:
+            depth--
+        case token.ILLEGAL:
+            if tok.Literal == lexer.UNTERMINATED_COMMENT || tok.Literal == lexer.UNTERMINATED_STRING {
+                return true
+            }
+        }
+        if tok.Type != token.COMMENT {
+            last = tok
+        }
+    }
+    if depth > 0 {
+        return true
+    }
+    if !continuationTokens[last.Type] {
+        return false
+    }
+
+    p := parser.New(lexer.New(input))
+    p.ParseProgram()
+    errors := p.Errors()
+
+    return len(errors) > 0 && errors[0].Actual.Type == token.EOF
 }
 
-func printParserErrors(out io.Writer, errors []string) {
-    for _, msg := range errors {
-        io.WriteString(out, "\t"+msg+"\n")
+func printParserErrors(out io.Writer, errors []*parser.ParseError) {
+    for _, err := range errors {
+        io.WriteString(out, "\t"+err.Error()+"\n")
     }
 }
@@ -0,0 +1,36 @@
+package repl
+
+import "testing"
+
+func TestIsIncomplete(t *testing.T) {
+    tests := []struct {
+        input string
+        expected bool
+    }{
+        {"1 + 2\n", false},
+        {"let add = fn(a, b) {\n", true},
+        {"let add = fn(a, b) {\n a + b\n}\n", false},
+        {"[1, 2,\n", true},
+        {"f(1,\n", true},
+        {"{\"a\": [1, (2\n", true},
+        {"\"multi\nline\n", true},
+        {"/* a\ncomment\n", true},
+        {"/* done */ 1\n", false},
+        {"let x\n", false},
+        {"1 +\n", true},
+        {"let x = \n", true},
+        {"let x = 1 +\n 2\n", false},
+        {"x +=\n", true},
+        {"1 + // more\n", true},
+        {"puts(1),\n", false},
+        {"let\n", false},
+        {")\n", false},
+        {"\"}\"\n", false},
+    }
+
+    for _, tt := range tests {
+        if got := isIncomplete(tt.input); got != tt.expected {
+            t.Errorf("isIncomplete(%q) = %t, want %t", tt.input, got, tt.expected)
+        }
+    }
+}
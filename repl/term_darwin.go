@@ -0,0 +1,8 @@
+package repl
+
+import "syscall"
+
+const (
+    ioctlGetTermios = syscall.TIOCGETA
+    ioctlSetTermios = syscall.TIOCSETA
+)
@@ -0,0 +1,21 @@
+//go:build !linux && !darwin
+
+package repl
+
+import "errors"
+
+type terminalState struct{}
+
+var errNoTerminal = errors.New("line editing is not supported on this platform")
+
+func getTerminalState(fd uintptr) (*terminalState, error) {
+    return nil, errNoTerminal
+}
+
+func setTerminalState(fd uintptr, state *terminalState) error {
+    return errNoTerminal
+}
+
+func makeRaw(fd uintptr) (*terminalState, error) {
+    return nil, errNoTerminal
+}
@@ -0,0 +1,54 @@
+//go:build linux || darwin
+
+package repl
+
+import (
+	"syscall"
+	"unsafe"
+)
+
+type terminalState struct {
+    termios syscall.Termios
+}
+
+func getTerminalState(fd uintptr) (*terminalState, error) {
+    var state terminalState
+
+    _, _, errno := syscall.Syscall(syscall.SYS_IOCTL, fd, ioctlGetTermios, uintptr(unsafe.Pointer(&state.termios)))
+    if errno != 0 {
+        return nil, errno
+    }
+
+    return &state, nil
+}
+
+func setTerminalState(fd uintptr, state *terminalState) error {
+    _, _, errno := syscall.Syscall(syscall.SYS_IOCTL, fd, ioctlSetTermios, uintptr(unsafe.Pointer(&state.termios)))
+    if errno != 0 {
+        return errno
+    }
+
+    return nil
+}
+
+// makeRaw switches the terminal to character-at-a-time input without echo
+// and returns the previous state so it can be restored. Output processing is
+// left untouched so "\n" still starts a new line.
+func makeRaw(fd uintptr) (*terminalState, error) {
+    old, err := getTerminalState(fd)
+    if err != nil {
+        return nil, err
+    }
+
+    raw := *old
+    raw.termios.Iflag &^= syscall.ICRNL | syscall.INLCR | syscall.IXON
+    raw.termios.Lflag &^= syscall.ECHO | syscall.ICANON | syscall.ISIG | syscall.IEXTEN
+    raw.termios.Cc[syscall.VMIN] = 1
+    raw.termios.Cc[syscall.VTIME] = 0
+
+    if err := setTerminalState(fd, &raw); err != nil {
+        return nil, err
+    }
+
+    return old, nil
+}
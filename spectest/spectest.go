@@ -0,0 +1,164 @@
+// Package spectest holds the test tables that pin down the language. The
+// evaluator and the vm both run them, so the two backends are held to the
+// same behaviour and any divergence fails a test.
+package spectest
+
+import (
+	"interpreter/object"
+	"testing"
+)
+
+// Case is a program and what it must evaluate to. Expected is one of
+//
+//	int, float64, bool, string  an integer, float, boolean or string
+//	[]int                       an array of integers
+//	BigInt                      an integer beyond int64, in decimal
+//	Inspect                     any object, by its Inspect output
+//	Error                       a runtime error, by its message
+//	nil                         null
+//	NoValue{}                   no value at all, like after a let
+type Case struct {
+    Input string
+    Expected interface{}
+}
+
+type BigInt string
+
+type Inspect string
+
+type Error string
+
+type NoValue struct{}
+
+// Table is a named group of cases, run with the given options.
+type Table struct {
+    Name string
+    Options object.Options
+    Cases []Case
+}
+
+// Backend runs a program with options and returns its result, or the error
+// that stopped it.
+type Backend func(input string, options object.Options) (object.Object, error)
+
+// Run runs every table on a backend, each as a subtest.
+func Run(t *testing.T, run Backend) {
+    for _, table := range Tables {
+        t.Run(table.Name, func(t *testing.T) {
+            RunCases(t, run, table.Options, table.Cases)
+        })
+    }
+}
+
+// RunCases runs the cases on a backend.
+func RunCases(t *testing.T, run Backend, options object.Options, cases []Case) {
+    t.Helper()
+
+    for _, tt := range cases {
+        result, err := run(tt.Input, options)
+
+        if expected, ok := tt.Expected.(Error); ok {
+            if err == nil {
+                t.Errorf("%q: expected error %q, got %T (%+v)", tt.Input, expected, result, result)
+            } else if err.Error() != string(expected) {
+                t.Errorf("%q: wrong error. expected=%q, got=%q", tt.Input, expected, err)
+            }
+            continue
+        }
+
+        if err != nil {
+            t.Errorf("%q: unexpected error: %s", tt.Input, err)
+            continue
+        }
+
+        testObject(t, tt.Input, tt.Expected, result)
+    }
+}
+
+func testObject(t *testing.T, input string, expected interface{}, actual object.Object) {
+    t.Helper()
+
+    switch expected := expected.(type) {
+    case int:
+        integer, ok := actual.(*object.Integer)
+        if !ok {
+            t.Errorf("%q: object is not Integer. got=%T (%+v)", input, actual, actual)
+            return
+        }
+        if integer.Value != int64(expected) {
+            t.Errorf("%q: object has wrong value. got=%d, want=%d", input, integer.Value, expected)
+        }
+
+    case float64:
+        float, ok := actual.(*object.Float)
+        if !ok {
+            t.Errorf("%q: object is not Float. got=%T (%+v)", input, actual, actual)
+            return
+        }
+        if float.Value != expected {
+            t.Errorf("%q: object has wrong value. got=%g, want=%g", input, float.Value, expected)
+        }
+
+    case bool:
+        boolean, ok := actual.(*object.Boolean)
+        if !ok {
+            t.Errorf("%q: object is not Boolean. got=%T (%+v)", input, actual, actual)
+            return
+        }
+        if boolean.Value != expected {
+            t.Errorf("%q: object has wrong value. got=%t, want=%t", input, boolean.Value, expected)
+        }
+
+    case string:
+        str, ok := actual.(*object.String)
+        if !ok {
+            t.Errorf("%q: object is not String. got=%T (%+v)", input, actual, actual)
+            return
+        }
+        if str.Value != expected {
+            t.Errorf("%q: object has wrong value. got=%q, want=%q", input, str.Value, expected)
+        }
+
+    case []int:
+        array, ok := actual.(*object.Array)
+        if !ok {
+            t.Errorf("%q: object not Array. got=%T (%+v)", input, actual, actual)
+            return
+        }
+        if len(array.Elements) != len(expected) {
+            t.Errorf("%q: wrong num of elements. want=%d, got=%d", input, len(expected), len(array.Elements))
+            return
+        }
+        for i, expectedElem := range expected {
+            testObject(t, input, expectedElem, array.Elements[i])
+        }
+
+    case BigInt:
+        bigInt, ok := actual.(*object.BigInt)
+        if !ok {
+            t.Errorf("%q: object is not BigInt. got=%T (%+v)", input, actual, actual)
+            return
+        }
+        if bigInt.Value.String() != string(expected) {
+            t.Errorf("%q: object has wrong value. got=%s, want=%s", input, bigInt.Value, expected)
+        }
+
+    case Inspect:
+        if actual == nil || actual.Inspect() != string(expected) {
+            t.Errorf("%q: wrong object. got=%T (%+v), want %s", input, actual, actual, expected)
+        }
+
+    case nil:
+        if _, ok := actual.(*object.Null); !ok {
+            t.Errorf("%q: object is not Null. got=%T (%+v)", input, actual, actual)
+        }
+
+    case NoValue:
+        if actual != nil {
+            t.Errorf("%q: expected no value. got=%T (%+v)", input, actual, actual)
+        }
+
+    default:
+        t.Fatalf("%q: unsupported expectation %T", input, expected)
+    }
+}
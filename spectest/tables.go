@@ -0,0 +1,653 @@
+package spectest
+
+import (
+	"interpreter/object"
+	"math"
+)
+
+// Tables lists every table, by topic.
+var Tables = []Table{
+    {Name: "IntegerArithmetic", Cases: IntegerArithmetic},
+    {Name: "FloatArithmetic", Cases: FloatArithmetic},
+    {Name: "BigIntegers", Cases: BigIntegers},
+    {Name: "BooleanExpressions", Cases: BooleanExpressions},
+    {Name: "Truthiness", Cases: Truthiness},
+    {Name: "Equality", Cases: Equality},
+    {Name: "ComparisonAndLogicalOperators", Cases: ComparisonAndLogicalOperators},
+    {Name: "ArithmeticAndBitwiseOperators", Cases: ArithmeticAndBitwiseOperators},
+    {Name: "CheckedOverflow", Options: object.Options{CheckOverflow: true}, Cases: CheckedOverflow},
+    {Name: "Conditionals", Cases: Conditionals},
+    {Name: "ReturnStatements", Cases: ReturnStatements},
+    {Name: "LetStatements", Cases: LetStatements},
+    {Name: "AssignExpressions", Cases: AssignExpressions},
+    {Name: "Loops", Cases: Loops},
+    {Name: "ForInLoops", Cases: ForInLoops},
+    {Name: "FunctionApplication", Cases: FunctionApplication},
+    {Name: "FunctionArguments", Cases: FunctionArguments},
+    {Name: "Closures", Cases: Closures},
+    {Name: "RecursiveFunctions", Cases: RecursiveFunctions},
+    {Name: "Strings", Cases: Strings},
+    {Name: "Arrays", Cases: Arrays},
+    {Name: "Hashes", Cases: Hashes},
+    {Name: "BuiltinFunctions", Cases: BuiltinFunctions},
+    {Name: "ErrorHandling", Cases: ErrorHandling},
+}
+
+var IntegerArithmetic = []Case{
+    {"5", 5},
+    {"10", 10},
+    {"-5", -5},
+    {"5 + 5 + 10 + 10", 30},
+    {"3 * 5  * 2 / 2", 15},
+    {"(1 + 1) * 3", 6},
+    {"0xff", 255},
+    {"0o755", 493},
+    {"0b1010 + 1", 11},
+    {"1_000_000 / 1_000", 1000},
+    {"-0x_7fff_ffff_ffff_ffff", -9223372036854775807},
+}
+
+var FloatArithmetic = []Case{
+    {"1.5", 1.5},
+    {"-2.5", -2.5},
+    {"1.5 + 1.5", 3.0},
+    {"1 + 0.5", 1.5},
+    {"0.5 + 1", 1.5},
+    {"10 - 2.5", 7.5},
+    {"2 * 0.25", 0.5},
+    {"1 / 4.0", 0.25},
+    {"7 / 2", 3},
+    {"1e3 / 10", 100.0},
+    {"1.5 < 2", true},
+    {"2 > 1.5", true},
+    {"2.5 >= 3", false},
+    {"1 == 1.0", true},
+    {"0.1 + 0.2 != 0.3", true},
+    {"2.5 == 2.5", true},
+    {"1.5", Inspect("1.5")},
+    {"2.0", Inspect("2.0")},
+    {"1 * 3.0", Inspect("3.0")},
+    {"1e21", Inspect("1e+21")},
+    {"-0.001", Inspect("-0.001")},
+}
+
+var BigIntegers = []Case{
+    {"123456789012345678901234567890", BigInt("123456789012345678901234567890")},
+    {"-123456789012345678901234567890", BigInt("-123456789012345678901234567890")},
+    {"9223372036854775808 - 1", 9223372036854775807},
+    {"-9223372036854775808", math.MinInt64},
+    {"9223372036854775807 + 1", BigInt("9223372036854775808")},
+    {"-9223372036854775807 - 2", BigInt("-9223372036854775809")},
+    {"4611686018427387904 * 2", BigInt("9223372036854775808")},
+    {"let min = -9223372036854775807 - 1; min / -1", BigInt("9223372036854775808")},
+    {"let min = -9223372036854775807 - 1; -min", BigInt("9223372036854775808")},
+    {"10000000000000000000 * 10000000000000000000", BigInt("100000000000000000000000000000000000000")},
+    {"100000000000000000000 / 10", BigInt("10000000000000000000")},
+    {"100000000000000000000 / 100", 1000000000000000000},
+    {"2 * 9223372036854775807 / 2", 9223372036854775807},
+    {"-(-9223372036854775807 - 1)", BigInt("9223372036854775808")},
+    {"100000000000000000000 > 1", true},
+    {"1 < 100000000000000000000", true},
+    {"100000000000000000000 == 100000000000000000000", true},
+    {"100000000000000000000 != 99999999999999999999", true},
+    {"100000000000000000000 == 1", false},
+    {"100000000000000000000 + 0.5", 1e20 + 0.5},
+    {"100000000000000000000 / 0", Error("division by zero: 100000000000000000000 / 0")},
+    {`{100000000000000000000: "big"}[100000000000000000000]`, "big"},
+    {"type(100000000000000000000)", "INTEGER"},
+    {"type(2 ** 63)", "INTEGER"},
+    {"type(-(2 ** 63))", "INTEGER"},
+    {`"a" + 2 ** 70`, Error("type mismatch: STRING + INTEGER")},
+    {"-(2 ** 70) + true", Error("type mismatch: INTEGER + BOOLEAN")},
+    {"len(2 ** 70)", Error("argument to `len` not supported, got INTEGER")},
+    {"range(2 ** 70)", Error("argument to `range` out of range: 1180591620717411303424")},
+    {"round(2 ** 70, 2)", 1180591620717411303424.0},
+    {"round(1.25, 2 ** 70)", 1.25},
+    {"round(1.25, -(2 ** 70))", 0.0},
+    {"str(9223372036854775807 + 1)", "9223372036854775808"},
+    {"int(1e20)", BigInt("100000000000000000000")},
+    {"int(\"100000000000000000000\") + 1", BigInt("100000000000000000001")},
+    {"float(100000000000000000000)", 1e20},
+    {"floor(100000000000000000000)", BigInt("100000000000000000000")},
+}
+
+var BooleanExpressions = []Case{
+    {"true", true},
+    {"false", false},
+    {"1 < 2", true},
+    {"1 > 2", false},
+    {"1 > 1", false},
+    {"1 == 1", true},
+    {"1 == 2", false},
+    {"1 != 1", false},
+    {"1 != 2", true},
+    {"true == true", true},
+    {"true == false", false},
+    {"false == false", true},
+    {"false == true", false},
+    {"!true", false},
+    {"!false", true},
+    {"!5", false},
+    {"!!true", true},
+    {"!!false", false},
+    {"!!5", true},
+}
+
+// Truthiness pins down which values count as true in conditions and as
+// operands of !, && and ||. Each value is tested in all four positions.
+var Truthiness = truthinessCases([]truthValue{
+    {"[][0]", false},
+    {"false", false},
+    {"true", true},
+    {"0", false},
+    {"-1", true},
+    {"2 ** 70", true},
+    {"0.0", false},
+    {"0.5", true},
+    {"\"\"", false},
+    {"\"0\"", true},
+    {"[]", false},
+    {"[0]", true},
+    {"{}", false},
+    {"{1: 2}", true},
+    {"range(0)", false},
+    {"range(5, 0, -1)", true},
+    {"fn() { 0 }", true},
+    {"len", true},
+})
+
+type truthValue struct {
+    value string
+    truthy bool
+}
+
+func truthinessCases(values []truthValue) []Case {
+    cases := []Case{}
+    for _, v := range values {
+        cases = append(cases,
+            Case{"if (" + v.value + ") { true } else { false }", v.truthy},
+            Case{"!!(" + v.value + ")", v.truthy},
+            Case{"let x = " + v.value + "; x && true || false", v.truthy},
+            Case{"let n = 0; while (" + v.value + ") { n += 1; break }; n == 1", v.truthy},
+        )
+    }
+
+    return cases
+}
+
+var Equality = []Case{
+    {`"a" == "a"`, true},
+    {`"a" != "a"`, false},
+    {`"a" == "b"`, false},
+    {`let s = "ab"; s == "a" + "b"`, true},
+    {`"abc" < "abd"`, true},
+    {`"b" > "abc"`, true},
+    {`"ab" < "abc"`, true},
+    {`"a" <= "a"`, true},
+    {`"b" >= "c"`, false},
+    {`"é" > "z"`, true},
+    {"[1, [2, 3]] == [1, [2, 3]]", true},
+    {"[1, 2] == [1, 2, 3]", false},
+    {"[1, 2] != [2, 1]", true},
+    {"[1, 2.0] == [1.0, 2]", true},
+    {"[] == []", true},
+    {`{"a": 1, "b": [2]} == {"b": [2], "a": 1}`, true},
+    {`{"a": 1} == {"a": 2}`, false},
+    {`{"a": 1} == {"b": 1}`, false},
+    {"let a = [1]; a[0] = a; let b = [1]; b[0] = b; a == b", true},
+    {"1 == 1.0", true},
+    {"2 ** 70 == 2 ** 70", true},
+    {`1 == "1"`, false},
+    {`"1" != 1`, true},
+    {"[] == {}", false},
+    {"true == 1", false},
+    {"[][0] == [][0]", true},
+    {"[][0] == false", false},
+    {"let f = fn() { 1 }; f == f", true},
+    {"fn() { 1 } == fn() { 1 }", false},
+    {"len == len", true},
+}
+
+var ComparisonAndLogicalOperators = []Case{
+    {"1 <= 2", true},
+    {"2 <= 2", true},
+    {"3 <= 2", false},
+    {"1 >= 2", false},
+    {"2 >= 2", true},
+    {"2.5 >= 2", true},
+    {"100000000000000000000 >= 100000000000000000000", true},
+    {"true && true", true},
+    {"true && false", false},
+    {"false || true", true},
+    {"false || false", false},
+    {"1 < 2 && 2 < 3", true},
+    {"1 > 2 || 2 > 3", false},
+    {"1 && 2", 2},
+    {"false && 2", false},
+    {"0 || 5", 5},
+    {"false || 5", 5},
+    {"false && 1 / 0", false},
+    {"true || 1 / 0", true},
+    {"let f = fn() { false && missing }; f()", false},
+    {"let f = fn() { true || missing }; f()", true},
+    {"let calls = [0]; let f = fn() { push(calls, 1) }; false && f(); len(calls)", 1},
+    {"if (1 < 2 && 3 > 2) { 10 } else { 20 }", 10},
+    {"let f = fn(a, b) { a || b }; f(false, 7)", 7},
+    {"let f = fn() { true && missing }; f()", Error("identifier not found: missing")},
+    {"let f = fn() { missing || true }; f()", Error("identifier not found: missing")},
+}
+
+var ArithmeticAndBitwiseOperators = []Case{
+    {"7 % 3", 1},
+    {"-7 % 3", -1},
+    {"7 % -3", 1},
+    {"2 ** 10", 1024},
+    {"2 ** 3 ** 2", 512},
+    {"-2 ** 2", -4},
+    {"(-2) ** 3", -8},
+    {"10 ** 0", 1},
+    {"2 ** -1", 0.5},
+    {"2 ** 0.5", math.Sqrt2},
+    {"7.5 % 2", 1.5},
+    {"2 ** 64", BigInt("18446744073709551616")},
+    {"(2 ** 64 + 5) % 7", 0},
+    {"0xff & 0x0f", 0x0f},
+    {"0xf0 | 0x0f", 0xff},
+    {"0xff ^ 0x0f", 0xf0},
+    {"~0", -1},
+    {"~5", -6},
+    {"1 << 10", 1024},
+    {"1024 >> 3", 128},
+    {"-16 >> 2", -4},
+    {"1 >> 100", 0},
+    {"1 << 64", BigInt("18446744073709551616")},
+    {"str(1 << 64)", "18446744073709551616"},
+    {"(1 << 64) >> 60", 16},
+    {"(1 << 64) | 1", BigInt("18446744073709551617")},
+    {"((1 << 64) + 0xff) & 0xf0", 0xf0},
+    {"~(1 << 64)", BigInt("-18446744073709551617")},
+    {"(-(1 << 64)) >> 100", -1},
+    {"1 + 2 & 3 == 3", true},
+    {"let h = 5381; let h = (h * 33 + 97) & 0xffffffff; h", 177670},
+    {"7 % 0", Error("division by zero: 7 % 0")},
+    {"7.5 % 0", Error("division by zero: 7.5 % 0")},
+    {"(1 << 64) % 0", Error("division by zero: 18446744073709551616 % 0")},
+    {"0 ** -1", Error("division by zero: 0 ** -1")},
+    {"1 << -1", Error("negative shift count: 1 << -1")},
+    {"1 >> -1", Error("negative shift count: 1 >> -1")},
+    {"2 ** 100000000", Error("integer too large: 2 ** 100000000")},
+    {"1 << 100000000", Error("integer too large: 1 << 100000000")},
+    {"1.5 & 1", Error("unknown operator: FLOAT & INTEGER")},
+    {"~1.5", Error("unknown operator: ~FLOAT")},
+    {"true ** 2", Error("type mismatch: BOOLEAN ** INTEGER")},
+}
+
+// CheckedOverflow runs with CheckOverflow set, so arithmetic that leaves the
+// int64 range is an error instead of a BigInt.
+var CheckedOverflow = []Case{
+    {"9223372036854775807 + 1", Error("integer overflow: 9223372036854775807 + 1")},
+    {"-9223372036854775807 - 2", Error("integer overflow: -9223372036854775807 - 2")},
+    {"4611686018427387904 * 2", Error("integer overflow: 4611686018427387904 * 2")},
+    {"let min = -9223372036854775807 - 1; min / -1", Error("integer overflow: -9223372036854775808 / -1")},
+    {"let min = -9223372036854775807 - 1; -min", Error("integer overflow: -(-9223372036854775808)")},
+    {"let x = 9223372036854775807; x += 1", Error("integer overflow: 9223372036854775807 + 1")},
+    {"2 ** 63", Error("integer overflow: 2 ** 63")},
+    {"1 << 63", Error("integer overflow: 1 << 63")},
+    {"3 << 62", Error("integer overflow: 3 << 62")},
+    {"9223372036854775806 + 1", math.MaxInt64},
+    {"-4611686018427387904 * 2", math.MinInt64},
+    {"2 ** 62", 1 << 62},
+    {"1 << 62", 1 << 62},
+}
+
+var Conditionals = []Case{
+    {"if (true) { 10 }", 10},
+    {"if (false) { 10 }", nil},
+    {"if (1) { 10 }", 10},
+    {"if (1 < 2) { 10 }", 10},
+    {"if (1 > 2) { 10 }", nil},
+    {"if (1 < 2) { 10 } else { 20 }", 10},
+    {"if (1 > 2) { 10 } else { 20 }", 20},
+    {"if (true) { let a = 1; }", nil},
+    {"if (true) {}", nil},
+}
+
+var ReturnStatements = []Case{
+    {"return 10; 9;", 10},
+    {"return 5+5; 9;", 10},
+    {"return 10 * 8; 150;", 80},
+    {`
+        if(10 > 1) {
+            if (10 > 1) {
+                return 10;
+            }
+            return 1;
+        }
+    `,
+    10,
+    },
+    {"let f = fn() { return 1; 9 }; let g = fn() { f(); 2 }; g()", 2},
+    {"let f = fn() { return 1 }; let g = fn() { let x = f(); x + 10 }; g()", 11},
+    {"let f = fn(x) { if (x > 0) { return x } else { return -x }; 0 }; f(-3) + f(4)", 7},
+    {"let f = fn(x) { if (x > 0) { if (x > 5) { return 2 } return 1 }; 0 }; f(9) * 100 + f(3) * 10 + f(-1)", 210},
+    {"let f = fn() { return 5 }; let s = 0; for (let i = 0; i < 3; i += 1) { s += f() }; s", 15},
+    {"let make = fn() { fn() { return 3; 4 } }; let h = make(); h() + h()", 6},
+    {"let outer = fn() { let inner = fn() { return 1 }; inner(); return 2 }; outer()", 2},
+    {"let f = fn(n) { if (n == 0) { return 0 }; n + f(n - 1) }; f(4)", 10},
+    {"let f = fn() { let x = if (true) { return 7 } else { 8 }; x + 100 }; f()", 7},
+}
+
+var LetStatements = []Case{
+    {"let a = 5; a", 5},
+    {"let a = 5 * 5; a", 25},
+    {"let a = 5 + 5; let b = a; b", 10},
+    {"let a = 1; let a = a + 1; a", 2},
+    {"let a = 5", NoValue{}},
+    {"if (true) { 1 } else { 2 }; let a = 3", NoValue{}},
+    {"let f = fn() { 1 }; f(); let a = 3", NoValue{}},
+}
+
+var AssignExpressions = []Case{
+    {"let x = 1; x = 5; x", 5},
+    {"let x = 1; x = x + 1", 2},
+    {"let x = 1; let y = 2; x = y = 7; x + y", 14},
+    {"let x = 10; x += 5; x -= 3; x *= 2; x /= 4; x", 6},
+    {"let s = \"a\"; s += \"b\"; s", "ab"},
+    {"let x = 1; let f = fn() { x = 2 }; f(); x", 2},
+    {"let x = 1; let f = fn(x) { x = 2 }; f(0); x", 1},
+    {"let f = fn(x) { x += 1; x * 10 }; f(1)", 20},
+    {"let f = fn() { let y = 1; y = y + 1; y }; f()", 2},
+    {"let counter = fn() { let n = 0; fn() { n += 1 } }; let c = counter(); c(); c(); c()", 3},
+    {"let counter = fn() { let n = 0; fn() { n += 1 } }; let a = counter(); let b = counter(); a(); a(); b()", 1},
+    {"let f = fn(n) { let inc = fn() { fn() { n = n * 2 } }; inc()(); inc()(); n }; f(3)", 12},
+    {"let f = fn() { let n = 1; let get = fn() { n }; n = 5; get() }; f()", 5},
+    {"let a = [1, 2, 3]; a[1] = 20; a[1] + a[2]", 23},
+    {"let a = [1, 2, 3]; a[0] *= 10", 10},
+    {"let h = {\"a\": 1}; h[\"a\"] += 1; h[\"b\"] = 5; h[\"a\"] + h[\"b\"]", 7},
+    {"x = 1", Error("identifier not found: x")},
+    {"let f = fn() { y += 1 }; f()", Error("identifier not found: y")},
+    {"len = 1", Error("cannot assign to builtin len")},
+    {"let x = 1; x += true", Error("type mismatch: INTEGER + BOOLEAN")},
+    {"let a = [1]; a[1] = 2", Error("index out of range: 1")},
+    {"let a = [1]; a[-1] = 2", Error("index out of range: -1")},
+    {"let h = {}; h[fn(x) { x }] = 1", Error("unusable as hash key: FUNCTION")},
+    {"let s = \"abc\"; s[0] = \"x\"", Error("index assignment not supported: STRING[INTEGER]")},
+}
+
+var Loops = []Case{
+    {"let i = 0; while (i < 5) { i += 1 }; i", 5},
+    {"let i = 0; while (i < 5) { i += 1 }", nil},
+    {"let n = 0; while (n < 100000) { n += 1 }; n", 100000},
+    {"let s = 0; for (let i = 1; i <= 10; i += 1) { s += i }; s", 55},
+    {"let s = 0; for (let i = 0; i < 10; i += 1) { if (i % 2 == 0) { continue }; s += i }; s", 25},
+    {"let i = 0; for (;;) { i += 1; if (i == 7) { break } }; i", 7},
+    {"let i = 0; while (true) { while (true) { break }; i += 1; if (i > 2) { break } }; i", 3},
+    {"let f = fn() { let i = 0; while (true) { i += 1; if (i == 4) { return i * 10 } } }; f()", 40},
+    {"let f = fn() { for (let i = 0; i < 3; i += 1) { } }; f()", nil},
+    {"let sum = fn(arr) { let s = 0; for (let i = 0; i < len(arr); i += 1) { s += arr[i] }; s }; sum([1, 2, 3, 4])", 10},
+    {"let f = fn() { let i = 0; let g = fn() { i }; while (i < 3) { i += 1 }; g() }; f()", 3},
+    {"let i = 0; while (i < 5000) { i += 1; [1, if (true) { continue }] }; i", 5000},
+    {"let s = 0; for (let x = 0; x < 5; x += 1) { s = s + (if (x % 2 == 0) { continue } else { x }) }; s", 4},
+    {"let f = fn(a, b) { a + b }; let n = 0; while (true) { n += 1; f(n, if (n == 3) { break } else { 0 }) }; n", 3},
+    {"let i = 0; while (i < 3) { i += 1; {\"a\": if (true) { continue }} }; i", 3},
+    {"let f = fn() { let s = 0; for (i in range(3)) { for (j in range(3)) { s += 10 * [1, if (j == 1) { break } else { 1 }][0] } }; s }; f()", 30},
+    {"while (1 + true) { }", Error("type mismatch: INTEGER + BOOLEAN")},
+    {"for (let i = 0; i < 3; i += true) { }", Error("type mismatch: INTEGER + BOOLEAN")},
+    {"let f = fn() { for (let i = 0; i < 3; i += 1) { missing } }; f()", Error("identifier not found: missing")},
+}
+
+var ForInLoops = []Case{
+    {"let s = 0; for (x in [1, 2, 3]) { s += x }; s", 6},
+    {"let s = 0; for (i, x in [5, 6, 7]) { s += i * x }; s", 20},
+    {"let s = \"\"; for (c in \"héllo\") { s += c + \".\" }; s", "h.é.l.l.o."},
+    {"let s = 0; for (i, c in \"日本語\") { s += i }; s", 3},
+    {"let s = \"\"; let h = {\"a\": 1, \"b\": 2}; for (k in h) { s += k }; s", "ab"},
+    {"let s = \"\"; let h = {\"a\": 1, \"b\": 2}; for (k, v in h) { s += k + str(v) }; s", "a1b2"},
+    {"let s = 0; for (n in range(5)) { s += n }; s", 10},
+    {"let s = 0; for (n in range(2, 5)) { s += n }; s", 9},
+    {"let a = []; for (n in range(10, 0, -3)) { let a = push(a, n) }; a", []int{10, 7, 4, 1}},
+    {"let a = []; for (i, n in range(5, 8)) { let a = push(a, i) }; a", []int{0, 1, 2}},
+    {"let s = 0; for (n in range(5, 0)) { s += 1 }; s", 0},
+    {"let s = 0; for (n in range(1000000000000)) { if (n == 3) { break }; s += n }; s", 3},
+    {"let s = 0; for (n in range(6)) { if (n % 2 == 0) { continue }; s += n }; s", 9},
+    {"let a = [1, 2, 3]; for (i, x in a) { a[i] = x * x }; a", []int{1, 4, 9}},
+    {"let r = []; for (x in [1, 2, 3]) { r = push(r, if (x == 2) { continue } else { x }) }; r", []int{1, 3}},
+    {"let r = []; for (x in range(5)) { r = push(r, if (x == 3) { break } else { x }) }; r", []int{0, 1, 2}},
+    {"let f = fn(xs) { let s = 0; for (i, x in xs) { for (y in range(x)) { s += i } }; s }; f([1, 2, 3])", 8},
+    {"let f = fn(xs) { for (x in xs) { if (x > 1) { return x } } }; f([0, 5, 9])", 5},
+    {"let f = fn() { let fs = []; for (x in [1,2]) { fs = push(fs, fn() { x }) }; fs[0]() }; f()", 2},
+    {"let f = fn() { let fs = []; for (i, x in [5, 6]) { fs = push(fs, fn() { i }) }; fs[0]() }; f()", 1},
+    {"let n = 0; for (x in []) { n += 1 }; n", 0},
+    {"for (x in []) { }", nil},
+    {"for (x in 5) { }", Error("not iterable: INTEGER")},
+    {"let f = fn() { for (x in missing) { } }; f()", Error("identifier not found: missing")},
+    {"for (x in [1]) { x + true }", Error("type mismatch: INTEGER + BOOLEAN")},
+    {"range()", Error("wrong number of arguments. got=0, want=1..3")},
+    {"range(1, \"a\")", Error("arguments to `range` must be INTEGER, got STRING")},
+    {"range(0, 10, 0)", Error("`range` step must not be zero")},
+}
+
+var FunctionApplication = []Case{
+    {"let identity = fn(x) { x; }; identity(5);", 5},
+    {"let identity = fn(x) { return x; }; identity(5);", 5},
+    {"let double = fn(x) { x * 2; }; double(5);", 10},
+    {"let sum = fn(x, y) { x + y ; }; sum(5, 5);", 10},
+    {"let sum = fn(x, y) { x + y ; }; sum(5 + 5, sum(5, 5));", 20},
+    {"fn(x) { x; }(5)", 5},
+    {"let noReturn = fn() { }; noReturn();", nil},
+    {"let f = fn() { g() }; let g = fn() { 3 }; f()", 3},
+}
+
+var FunctionArguments = []Case{
+    {"let f = fn(a, b = 10) { a + b }; f(1)", 11},
+    {"let f = fn(a, b = 10) { a + b }; f(1, 2)", 3},
+    {"let f = fn(a, b = a * 2) { a + b }; f(4)", 12},
+    {"let f = fn(a, ...rest) { rest }; f(1, 2, 3)", []int{2, 3}},
+    {"let f = fn(a, ...rest) { rest }; f(1)", []int{}},
+    {"let f = fn(...xs) { xs }; f(...[1, 2, 3])", []int{1, 2, 3}},
+    {"let f = fn(...xs) { xs[0] + xs[2] }; f(...[1, 2, 3])", 4},
+    {"let f = fn(a, b, c) { [a, b, c] }; f(1, ...range(2, 4))", []int{1, 2, 3}},
+    {"let f = fn(a, b) { a - b }; f(b: 1, a: 5)", 4},
+    {"let f = fn(a, b = 2, c = 3) { a + b * c }; f(1, c: 10)", 21},
+    {"let f = fn(a = 1) { let g = fn() { a += 1; a }; g() }; f()", 2},
+    {"let f = fn(a = fn() { return 2 }) { a() }; f()", 2},
+    {"let n = 0; while (true) { let f = fn(a = fn() { while (true) { break }; 3 }) { a() }; n = f(); break }; n", 3},
+    {"let f = fn(a = missing) { a }; f()", Error("identifier not found: missing")},
+    {"let f = fn(a) { a }; f(b: 1)", Error("unknown argument b to `f`")},
+    {"let f = fn(a) { a }; f(1, a: 2)", Error("duplicate argument a to `f`")},
+    {"let f = fn(a, b) { a }; f(b: 1)", Error("missing argument a to `f`")},
+    {"let f = fn(a, ...rest) { a }; f()", Error("wrong number of arguments to `f`: want=at least 1, got=0")},
+    {"let f = fn(a, b = 1) { a }; f(1, 2, 3)", Error("wrong number of arguments to `f`: want=1..2, got=3")},
+    {"len(x: \"a\")", Error("builtin functions do not take named arguments")},
+    {"let f = fn(a) { a }; f(...5)", Error("cannot spread INTEGER")},
+}
+
+var Closures = []Case{
+    {`
+    let newAdder = fn(a) { fn(b) { a + b } };
+    let addTwo = newAdder(2);
+    addTwo(3);
+    `, 5},
+    {`
+    let newAdderOuter = fn(a, b) {
+        let c = a + b;
+        fn(d) {
+            let e = d + c;
+            fn(f) { e + f; };
+        };
+    };
+    let newAdderInner = newAdderOuter(1, 2)
+    let adder = newAdderInner(3);
+    adder(8);
+    `, 14},
+    {"let f = fn() { let n = 1; let get = fn() { n }; let n = 5; get() }; f()", 5},
+    {"let f = fn(n) { let get = fn() { n }; let n = 5; get() }; f(1)", 5},
+    {`
+    let f = fn() {
+        let fs = [];
+        let i = 0;
+        while (i < 2) {
+            let n = i;
+            fs = push(fs, fn() { n });
+            i = i + 1;
+        }
+        fs[0]();
+    };
+    f();
+    `, 1},
+}
+
+var RecursiveFunctions = []Case{
+    {"let f = fn(n) { if (n == 0) { 0 } else { 1 + f(n - 1) } }; f(2000)", 2000},
+    {`
+    let fibonacci = fn(x) {
+        if (x == 0) { return 0; }
+        if (x == 1) { return 1; }
+        fibonacci(x - 1) + fibonacci(x - 2);
+    };
+    fibonacci(15);
+    `, 610},
+    {`
+    let wrapper = fn() {
+        let countDown = fn(x) {
+            if (x == 0) { return 0; }
+            countDown(x - 1);
+        };
+        countDown(1);
+    };
+    wrapper();
+    `, 0},
+    {"let f = fn() { let a = fn() { b() }; let b = fn() { 1 }; a() }; f()", 1},
+    {`
+    let f = fn() {
+        let even = fn(n) { if (n == 0) { true } else { odd(n - 1) } };
+        let odd = fn(n) { if (n == 0) { false } else { even(n - 1) } };
+        even(10);
+    };
+    f();
+    `, true},
+    {"let f = fn() { let a = fn() { b }; let x = a(); let b = 1; x }; f()", Error("identifier not found: b")},
+}
+
+var Strings = []Case{
+    {`"Hello world"`, "Hello world"},
+    {`"Hello" + " " + "World!"`, "Hello World!"},
+}
+
+var Arrays = []Case{
+    {"[]", []int{}},
+    {"[1, 2 * 2, 3 + 3]", []int{1, 4, 6}},
+    {"[1, 2, 3][0]", 1},
+    {"[1, 2, 3][1]", 2},
+    {"[1, 2, 3][2]", 3},
+    {"let i = 0; [1][i];", 1},
+    {"[1, 2, 3][1 + 1];", 3},
+    {"let myArray = [1, 2, 3]; myArray[2];", 3},
+    {"let myArray = [1, 2, 3]; myArray[0] + myArray[1] + myArray[2];", 6},
+    {"let myArray = [1, 2, 3]; let i = myArray[0]; myArray[i]", 2},
+    {"[1, 2, 3][3]", nil},
+    {"[1, 2, 3][-1]", nil},
+    {"let a = [1]; a[0] = a; a", Inspect("[[...]]")},
+    {"let a = [1, 2]; a[1] = a; str(a)", "[1, [...]]"},
+    {"let a = [1]; [a, a]", Inspect("[[1], [1]]")},
+}
+
+var Hashes = []Case{
+    {`let two = "two";
+    {
+        "one": 10 - 9,
+        two: 1 + 1,
+        "thr" + "ee": 6 / 2,
+        4: 4,
+        true: 5,
+        false: 6
+    }`, Inspect("{one: 1, two: 2, three: 3, 4: 4, true: 5, false: 6}")},
+    {`{"foo": 5}["foo"]`, 5},
+    {`{"foo": 5}["bar"]`, nil},
+    {`let key = "foo"; {"foo": 5}[key]`, 5},
+    {`{}["foo"]`, nil},
+    {`{5: 5}[5]`, 5},
+    {`{true: 5}[true]`, 5},
+    {`{false: 5}[false]`, 5},
+    {`let h = {}; h["self"] = h; h`, Inspect("{self: {...}}")},
+    {`let h = {}; let a = [h]; h["a"] = a; str(a)`, "[{a: [...]}]"},
+}
+
+var BuiltinFunctions = []Case{
+    {`len("")`, 0},
+    {`len("four")`, 4},
+    {`len("hello world")`, 11},
+    {`len("héllo")`, 5},
+    {`len(1)`, Error("argument to `len` not supported, got INTEGER")},
+    {`len("one", "two")`, Error("wrong number of arguments. got=2, want=1")},
+    {`len([1, 2, 3])`, 3},
+    {`len([])`, 0},
+    {`len({"a": 1, "b": 2})`, 2},
+    {`first([1, 2, 3])`, 1},
+    {`first([])`, nil},
+    {`first(1)`, Error("argument to `first` must be ARRAY, got INTEGER")},
+    {`last([1, 2, 3])`, 3},
+    {`last([])`, nil},
+    {`last(1)`, Error("argument to `last` must be ARRAY, got INTEGER")},
+    {`rest([1, 2, 3])`, []int{2, 3}},
+    {`rest([])`, nil},
+    {`push([], 1)`, []int{1}},
+    {`push(1, 1)`, Error("argument to `push` must be ARRAY, got INTEGER")},
+    {`let a = [1]; push(a, 2); a`, []int{1}},
+    {`puts()`, nil},
+    {`type(1)`, "INTEGER"},
+    {`type("a")`, "STRING"},
+    {`type([1])`, "ARRAY"},
+    {`type(len)`, "BUILTIN"},
+    {`type(fn() {})`, "FUNCTION"},
+    {`type(1.5)`, "FLOAT"},
+    {`str(12) + "3"`, "123"},
+    {`str([1, "a"])`, "[1, a]"},
+    {`str(0.5)`, "0.5"},
+    {`int("42") + 1`, 43},
+    {`int(" 0x10 ")`, 16},
+    {`int(true)`, 1},
+    {`int(false)`, 0},
+    {`int("abc")`, Error("could not convert \"abc\" to INTEGER")},
+    {`int([])`, Error("argument to `int` not supported, got ARRAY")},
+    {`int(2.9)`, 2},
+    {`int(-2.9)`, -2},
+    {`float(3)`, 3.0},
+    {`float("2.5")`, 2.5},
+    {`float(true)`, 1.0},
+    {`float("x")`, Error("could not convert \"x\" to FLOAT")},
+    {`floor(2.7)`, 2},
+    {`floor(-2.2)`, -3},
+    {`ceil(2.2)`, 3},
+    {`ceil(5)`, 5},
+    {`round(2.5)`, 3},
+    {`round(2.4)`, 2},
+    {`round(12.3456, 2)`, 12.35},
+    {`round(1 / 3.0 * 100, 1)`, 33.3},
+    {`floor("a")`, Error("argument to `floor` must be INTEGER or FLOAT, got STRING")},
+    {`floor(float("inf"))`, Error("could not convert +Inf to INTEGER")},
+}
+
+var ErrorHandling = []Case{
+    {"5 + true;", Error("type mismatch: INTEGER + BOOLEAN")},
+    {"5 + true; 5;", Error("type mismatch: INTEGER + BOOLEAN")},
+    {"-true;", Error("unknown operator: -BOOLEAN")},
+    {"true + true;", Error("unknown operator: BOOLEAN + BOOLEAN")},
+    {"5; false + true;", Error("unknown operator: BOOLEAN + BOOLEAN")},
+    {"foobar", Error("identifier not found: foobar")},
+    {`"Hello" - "World!"`, Error("unknown operator: STRING - STRING")},
+    {`"a" < 1`, Error("type mismatch: STRING < INTEGER")},
+    {`{"name": "Monkey"}[fn(x) { x }];`, Error("unusable as hash key: FUNCTION")},
+    {`{[1]: 2}`, Error("unusable as hash key: ARRAY")},
+    {"let f = fn() { missing }; f()", Error("identifier not found: missing")},
+    {"1()", Error("not a function INTEGER")},
+    {"1 / 0", Error("division by zero: 1 / 0")},
+    {"let x = 0; 10 / x + 1", Error("division by zero: 10 / 0")},
+    {"let f = fn(x) { 10 / x }; f(0)", Error("division by zero: 10 / 0")},
+    {"1.5 / 0", Error("division by zero: 1.5 / 0")},
+    {"2 / 0.0", Error("division by zero: 2 / 0.0")},
+    {"let add = fn(a, b) { a + b }; add(1)", Error("wrong number of arguments to `add`: want=2, got=1")},
+    {"let add = fn(a, b) { a + b }; add(1, 2, 3)", Error("wrong number of arguments to `add`: want=2, got=3")},
+    {"fn(x) { x }()", Error("wrong number of arguments to anonymous function: want=1, got=0")},
+    {"let f = fn() { 1 }; let g = fn(x) { f(x) }; g(1)", Error("wrong number of arguments to `f`: want=0, got=1")},
+}
@@ -1,10 +1,43 @@
 package token
 
+import "fmt"
+
 type TokenType string
 
+// Position describes a location in the source. Line and Column are 1-based,
+// Offset is the 0-based byte offset into the input.
+type Position struct {
+    Filename string
+    Offset int
+    Line int
+    Column int
+}
+
+// IsValid reports whether the position has been set by the lexer.
+func (p Position) IsValid() bool {
+    return p.Line > 0
+}
+
+func (p Position) String() string {
+    s := p.Filename
+    if p.IsValid() {
+        if s != "" {
+            s += ":"
+        }
+        s += fmt.Sprintf("%d:%d", p.Line, p.Column)
+    }
+    if s == "" {
+        s = "-"
+    }
+
+    return s
+}
+
 type Token struct {
     Type TokenType
     Literal string
+    Pos Position //position of the first character of the token
+    End Position //position immediately after the token
 }
 
 const (
@@ -12,33 +45,60 @@ const (
     EOF = "EOF"
     IDENT = "IDENT"
     INT = "INT"
+    FLOAT = "FLOAT"
     STRING = "STRING"
+    COMMENT = "COMMENT"
 
     ASSIGN = "="
+    PLUS_ASSIGN = "+="
+    MINUS_ASSIGN = "-="
+    ASTERISK_ASSIGN = "*="
+    SLASH_ASSIGN = "/="
     PLUS = "+"
     MINUS = "-"
     BANG = "!"
     ASTERISK = "*"
     SLASH = "/"
+    PERCENT = "%"
+    POWER = "**"
+    BIT_AND = "&"
+    BIT_OR = "|"
+    BIT_XOR = "^"
+    BIT_NOT = "~"
+    SHL = "<<"
+    SHR = ">>"
     LT = "<"
     GT = ">"
     COMMA = ","
     SEMICOLON = ";"
+    COLON = ":"
+    ELLIPSIS = "..."
     LPAREN = "("
     RPAREN = ")"
     LBRACE  = "{"
     RBRACE  = "}"
+    LBRACKET = "["
+    RBRACKET = "]"
 
     FUNCTION = "FUNCTION"
     LET = "LET"
     IF = "IF"
     ELSE = "ELSE"
     RETURN = "RETURN" 
+    WHILE = "WHILE"
+    FOR = "FOR"
+    BREAK = "BREAK"
+    CONTINUE = "CONTINUE"
+    IN = "IN"
     TRUE = "TRUE"
     FALSE = "FALSE"
     
     EQ = "=="
     NOT_EQ = "!="
+    LT_EQ = "<="
+    GT_EQ = ">="
+    AND = "&&"
+    OR = "||"
 )
 
 var keywords = map[string] TokenType {
@@ -47,6 +107,11 @@ var keywords = map[string] TokenType {
     "if": IF,
     "else": ELSE,
     "return": RETURN,
+    "while": WHILE,
+    "for": FOR,
+    "break": BREAK,
+    "continue": CONTINUE,
+    "in": IN,
     "true": TRUE,
     "false": FALSE,
 }
@@ -0,0 +1,21 @@
+package vm
+
+import (
+	"interpreter/code"
+	"interpreter/object"
+)
+
+type Frame struct {
+    cl *object.Closure
+    ip int
+    basePointer int
+    loops []int //stack pointers at the start of the running loops, innermost last
+}
+
+func NewFrame(cl *object.Closure, basePointer int) *Frame {
+    return &Frame{cl: cl, ip: -1, basePointer: basePointer}
+}
+
+func (f *Frame) Instructions() code.Instructions {
+    return f.cl.Fn.Instructions
+}
@@ -0,0 +1,740 @@
+package vm
+
+import (
+	"errors"
+	"fmt"
+	"interpreter/code"
+	"interpreter/compiler"
+	"interpreter/evaluator"
+	"interpreter/object"
+)
+
+const (
+    StackSize = 2048 //initial size of the stack, it grows up to MaxStackSize
+    MaxStackSize = 1 << 22
+    GlobalsSize = 65536
+    MaxFrames = 1 << 18
+)
+
+var (
+    True = evaluator.TRUE
+    False = evaluator.FALSE
+    Null = evaluator.NULL
+)
+
+var prefixOperators = map[code.Opcode]string{
+    code.OpMinus: "-",
+    code.OpBang: "!",
+    code.OpBitNot: "~",
+}
+
+var infixOperators = map[code.Opcode]string{
+    code.OpAdd: "+",
+    code.OpSub: "-",
+    code.OpMul: "*",
+    code.OpDiv: "/",
+    code.OpMod: "%",
+    code.OpPow: "**",
+    code.OpBitAnd: "&",
+    code.OpBitOr: "|",
+    code.OpBitXor: "^",
+    code.OpShiftLeft: "<<",
+    code.OpShiftRight: ">>",
+    code.OpEqual: "==",
+    code.OpNotEqual: "!=",
+    code.OpLessThan: "<",
+    code.OpGreaterThan: ">",
+    code.OpLessEqual: "<=",
+    code.OpGreaterEqual: ">=",
+}
+
+type VM struct {
+    constants []object.Object
+    globalNames []string
+
+    stack []object.Object
+    sp int //always points to the next free slot, the top of the stack is stack[sp-1]
+
+    globals []object.Object
+
+    frames []*Frame
+    framesIndex int
+
+    lastPopped object.Object //value of the last expression statement
+
+    options object.Options
+}
+
+func New(bytecode *compiler.Bytecode) *VM {
+    return NewWithGlobalsStore(bytecode, make([]object.Object, GlobalsSize))
+}
+
+func NewWithOptions(bytecode *compiler.Bytecode, options object.Options) *VM {
+    vm := New(bytecode)
+    vm.options = options
+
+    return vm
+}
+
+func NewWithGlobalsStore(bytecode *compiler.Bytecode, s []object.Object) *VM {
+    mainFn := &object.CompiledFunction{Instructions: bytecode.Instructions}
+    mainClosure := &object.Closure{Fn: mainFn}
+    mainFrame := NewFrame(mainClosure, 0)
+
+    frames := []*Frame{mainFrame}
+
+    return &VM{
+        constants: bytecode.Constants,
+        globalNames: bytecode.GlobalNames,
+
+        stack: make([]object.Object, StackSize),
+        sp: 0,
+
+        globals: s,
+
+        frames: frames,
+        framesIndex: 1,
+    }
+}
+
+func (vm *VM) currentFrame() *Frame {
+    return vm.frames[vm.framesIndex-1]
+}
+
+func (vm *VM) pushFrame(f *Frame) error {
+    if vm.framesIndex >= MaxFrames {
+        return errors.New("stack overflow")
+    }
+
+    vm.frames = append(vm.frames[:vm.framesIndex], f)
+    vm.framesIndex++
+
+    return nil
+}
+
+func (vm *VM) popFrame() *Frame {
+    vm.framesIndex--
+    return vm.frames[vm.framesIndex]
+}
+
+// LastPoppedStackElem returns the value of the last expression statement,
+// which is the result of the program.
+func (vm *VM) LastPoppedStackElem() object.Object {
+    return vm.lastPopped
+}
+
+func (vm *VM) Run() error {
+    var ip int
+    var ins code.Instructions
+    var op code.Opcode
+
+    for vm.currentFrame().ip < len(vm.currentFrame().Instructions())-1 {
+        vm.currentFrame().ip++
+
+        ip = vm.currentFrame().ip
+        ins = vm.currentFrame().Instructions()
+        op = code.Opcode(ins[ip])
+
+        switch op {
+        case code.OpConstant:
+            constIndex := code.ReadUint16(ins[ip+1:])
+            vm.currentFrame().ip += 2
+
+            if err := vm.push(vm.constants[constIndex]); err != nil {
+                return err
+            }
+
+        case code.OpPop:
+            vm.lastPopped = vm.pop()
+
+        case code.OpTrue:
+            if err := vm.push(True); err != nil {
+                return err
+            }
+
+        case code.OpFalse:
+            if err := vm.push(False); err != nil {
+                return err
+            }
+
+        case code.OpNull:
+            if err := vm.push(Null); err != nil {
+                return err
+            }
+
+        case code.OpAdd, code.OpSub, code.OpMul, code.OpDiv, code.OpMod, code.OpPow,
+            code.OpBitAnd, code.OpBitOr, code.OpBitXor, code.OpShiftLeft, code.OpShiftRight,
+            code.OpEqual, code.OpNotEqual, code.OpLessThan, code.OpGreaterThan,
+            code.OpLessEqual, code.OpGreaterEqual:
+            right := vm.pop()
+            left := vm.pop()
+
+            if err := vm.pushResult(evaluator.EvalInfix(infixOperators[op], left, right, vm.options)); err != nil {
+                return err
+            }
+
+        case code.OpMinus, code.OpBang, code.OpBitNot:
+            right := vm.pop()
+
+            if err := vm.pushResult(evaluator.EvalPrefix(prefixOperators[op], right, vm.options)); err != nil {
+                return err
+            }
+
+        case code.OpJump:
+            pos := int(code.ReadUint16(ins[ip+1:]))
+            vm.currentFrame().ip = pos - 1
+
+        case code.OpLoop:
+            frame := vm.currentFrame()
+            frame.loops = append(frame.loops, vm.sp)
+
+        case code.OpLoopEnd:
+            frame := vm.currentFrame()
+            frame.loops = frame.loops[:len(frame.loops)-1]
+
+        case code.OpLoopJump:
+            pos := int(code.ReadUint16(ins[ip+1:]))
+            frame := vm.currentFrame()
+            vm.sp = frame.loops[len(frame.loops)-1]
+            frame.ip = pos - 1
+
+        case code.OpJumpNotTruthy:
+            pos := int(code.ReadUint16(ins[ip+1:]))
+            vm.currentFrame().ip += 2
+
+            condition := vm.pop()
+            if !evaluator.IsTruthy(condition) {
+                vm.currentFrame().ip = pos - 1
+            }
+
+        case code.OpJumpIfBound:
+            localIndex := code.ReadUint8(ins[ip+1:])
+            pos := int(code.ReadUint16(ins[ip+2:]))
+            vm.currentFrame().ip += 3
+
+            if vm.stack[vm.currentFrame().basePointer+int(localIndex)] != nil {
+                vm.currentFrame().ip = pos - 1
+            }
+
+        case code.OpJumpNotTruthyOrPop, code.OpJumpTruthyOrPop:
+            pos := int(code.ReadUint16(ins[ip+1:]))
+            vm.currentFrame().ip += 2
+
+            condition := vm.stack[vm.sp-1]
+            if evaluator.IsTruthy(condition) == (op == code.OpJumpTruthyOrPop) {
+                vm.currentFrame().ip = pos - 1
+            } else {
+                vm.pop()
+            }
+
+        case code.OpSetGlobal:
+            globalIndex := code.ReadUint16(ins[ip+1:])
+            vm.currentFrame().ip += 2
+
+            vm.globals[globalIndex] = vm.pop()
+            // a let is the last statement so far and has no value, like in
+            // the evaluator
+            vm.lastPopped = nil
+
+        case code.OpIterator:
+            iterable, ok := vm.pop().(object.Iterable)
+            if !ok {
+                return fmt.Errorf("not iterable: %s", object.TypeName(vm.stack[vm.sp]))
+            }
+
+            if err := vm.push(iterable.Iterator()); err != nil {
+                return err
+            }
+
+        case code.OpIterNext:
+            pos := int(code.ReadUint16(ins[ip+1:]))
+            numVars := int(code.ReadUint8(ins[ip+3:]))
+            vm.currentFrame().ip += 3
+
+            if err := vm.iterNext(pos, numVars); err != nil {
+                return err
+            }
+
+        case code.OpAssignGlobal:
+            globalIndex := code.ReadUint16(ins[ip+1:])
+            vm.currentFrame().ip += 2
+
+            if vm.globals[globalIndex] == nil {
+                return fmt.Errorf("identifier not found: %s", vm.globalName(int(globalIndex)))
+            }
+            vm.globals[globalIndex] = vm.pop()
+
+        case code.OpGetGlobal:
+            globalIndex := code.ReadUint16(ins[ip+1:])
+            vm.currentFrame().ip += 2
+
+            global := vm.globals[globalIndex]
+            if global == nil {
+                return fmt.Errorf("identifier not found: %s", vm.globalName(int(globalIndex)))
+            }
+
+            if err := vm.push(global); err != nil {
+                return err
+            }
+
+        case code.OpSetLocal:
+            localIndex := code.ReadUint8(ins[ip+1:])
+            vm.currentFrame().ip += 1
+
+            frame := vm.currentFrame()
+            vm.stack[frame.basePointer+int(localIndex)] = vm.pop()
+
+        case code.OpGetLocal:
+            localIndex := code.ReadUint8(ins[ip+1:])
+            vm.currentFrame().ip += 1
+
+            frame := vm.currentFrame()
+            local := vm.stack[frame.basePointer+int(localIndex)]
+            if local == nil {
+                return fmt.Errorf("identifier not found: %s", frame.cl.Fn.LocalNames[localIndex])
+            }
+
+            if err := vm.push(local); err != nil {
+                return err
+            }
+
+        case code.OpSetLocalCell:
+            localIndex := code.ReadUint8(ins[ip+1:])
+            vm.currentFrame().ip += 1
+
+            slot := vm.currentFrame().basePointer + int(localIndex)
+            value := vm.pop()
+            if cell, ok := vm.stack[slot].(*object.Cell); ok {
+                cell.Value = value
+            } else {
+                vm.stack[slot] = &object.Cell{Value: value}
+            }
+
+        case code.OpGetLocalCell:
+            localIndex := code.ReadUint8(ins[ip+1:])
+            vm.currentFrame().ip += 1
+
+            frame := vm.currentFrame()
+            cell, ok := vm.stack[frame.basePointer+int(localIndex)].(*object.Cell)
+            if !ok || cell.Value == nil {
+                return fmt.Errorf("identifier not found: %s", frame.cl.Fn.LocalNames[localIndex])
+            }
+
+            if err := vm.push(cell.Value); err != nil {
+                return err
+            }
+
+        case code.OpLocalCell:
+            localIndex := code.ReadUint8(ins[ip+1:])
+            vm.currentFrame().ip += 1
+
+            slot := vm.currentFrame().basePointer + int(localIndex)
+            cell, ok := vm.stack[slot].(*object.Cell)
+            if !ok {
+                cell = &object.Cell{}
+                vm.stack[slot] = cell
+            }
+
+            if err := vm.push(cell); err != nil {
+                return err
+            }
+
+        case code.OpGetBuiltin:
+            builtinIndex := code.ReadUint8(ins[ip+1:])
+            vm.currentFrame().ip += 1
+
+            definition := object.Builtins[builtinIndex]
+            if err := vm.push(definition.Builtin); err != nil {
+                return err
+            }
+
+        case code.OpGetFree:
+            freeIndex := code.ReadUint8(ins[ip+1:])
+            vm.currentFrame().ip += 1
+
+            currentClosure := vm.currentFrame().cl
+            if err := vm.push(currentClosure.Free[freeIndex]); err != nil {
+                return err
+            }
+
+        case code.OpGetFreeCell:
+            freeIndex := code.ReadUint8(ins[ip+1:])
+            vm.currentFrame().ip += 1
+
+            cl := vm.currentFrame().cl
+            cell, ok := cl.Free[freeIndex].(*object.Cell)
+            if !ok {
+                return fmt.Errorf("free variable %d is not a cell", freeIndex)
+            }
+            if cell.Value == nil {
+                return fmt.Errorf("identifier not found: %s", cl.Fn.FreeNames[freeIndex])
+            }
+
+            if err := vm.push(cell.Value); err != nil {
+                return err
+            }
+
+        case code.OpSetFreeCell:
+            freeIndex := code.ReadUint8(ins[ip+1:])
+            vm.currentFrame().ip += 1
+
+            cell, ok := vm.currentFrame().cl.Free[freeIndex].(*object.Cell)
+            if !ok {
+                return fmt.Errorf("free variable %d is not a cell", freeIndex)
+            }
+            cell.Value = vm.pop()
+
+        case code.OpCurrentClosure:
+            if err := vm.push(vm.currentFrame().cl); err != nil {
+                return err
+            }
+
+        case code.OpArray:
+            numElements := int(code.ReadUint16(ins[ip+1:]))
+            vm.currentFrame().ip += 2
+
+            elements := make([]object.Object, numElements)
+            copy(elements, vm.stack[vm.sp-numElements:vm.sp])
+            vm.sp = vm.sp - numElements
+
+            if err := vm.push(&object.Array{Elements: elements}); err != nil {
+                return err
+            }
+
+        case code.OpHash:
+            numElements := int(code.ReadUint16(ins[ip+1:]))
+            vm.currentFrame().ip += 2
+
+            hash, err := vm.buildHash(vm.sp-numElements, vm.sp)
+            if err != nil {
+                return err
+            }
+            vm.sp = vm.sp - numElements
+
+            if err := vm.push(hash); err != nil {
+                return err
+            }
+
+        case code.OpIndex:
+            index := vm.pop()
+            left := vm.pop()
+
+            if err := vm.pushResult(evaluator.EvalIndex(left, index)); err != nil {
+                return err
+            }
+
+        case code.OpSetIndex:
+            value := vm.pop()
+            index := vm.pop()
+            left := vm.pop()
+
+            if err := vm.pushResult(evaluator.EvalSetIndex(left, index, value)); err != nil {
+                return err
+            }
+
+        case code.OpDup2:
+            if err := vm.push(vm.stack[vm.sp-2]); err != nil {
+                return err
+            }
+            if err := vm.push(vm.stack[vm.sp-2]); err != nil {
+                return err
+            }
+
+        case code.OpCall:
+            numArgs := code.ReadUint8(ins[ip+1:])
+            vm.currentFrame().ip += 1
+
+            if err := vm.executeCall(int(numArgs), nil); err != nil {
+                return err
+            }
+
+        case code.OpSpread:
+            values, err := evaluator.Spread(vm.pop())
+            if err != nil {
+                return errors.New(err.Message)
+            }
+
+            if err := vm.push(&spreadArguments{values: values}); err != nil {
+                return err
+            }
+
+        case code.OpCallArgs:
+            numPositional := code.ReadUint8(ins[ip+1:])
+            numNamed := code.ReadUint8(ins[ip+2:])
+            vm.currentFrame().ip += 2
+
+            if err := vm.executeCallArgs(int(numPositional), int(numNamed)); err != nil {
+                return err
+            }
+
+        case code.OpReturnValue:
+            returnValue := vm.pop()
+
+            if vm.framesIndex == 1 {
+                // a return at the top level ends the program with its value
+                vm.lastPopped = returnValue
+                return nil
+            }
+
+            frame := vm.popFrame()
+            vm.sp = frame.basePointer - 1
+
+            if err := vm.push(returnValue); err != nil {
+                return err
+            }
+
+        case code.OpReturn:
+            frame := vm.popFrame()
+            vm.sp = frame.basePointer - 1
+
+            if err := vm.push(Null); err != nil {
+                return err
+            }
+
+        case code.OpClosure:
+            constIndex := code.ReadUint16(ins[ip+1:])
+            numFree := code.ReadUint8(ins[ip+3:])
+            vm.currentFrame().ip += 3
+
+            if err := vm.pushClosure(int(constIndex), int(numFree)); err != nil {
+                return err
+            }
+
+        default:
+            def, err := code.Lookup(byte(op))
+            if err != nil {
+                return err
+            }
+            return fmt.Errorf("unhandled opcode %s", def.Name)
+        }
+    }
+
+    return nil
+}
+
+func (vm *VM) push(o object.Object) error {
+    if err := vm.growStack(vm.sp + 1); err != nil {
+        return err
+    }
+
+    vm.stack[vm.sp] = o
+    vm.sp++
+
+    return nil
+}
+
+func (vm *VM) pop() object.Object {
+    o := vm.stack[vm.sp-1]
+    vm.sp--
+
+    return o
+}
+
+// pushResult pushes the result of an operation, turning an error object
+// into a runtime error that stops the vm.
+func (vm *VM) pushResult(result object.Object) error {
+    if errObj, ok := result.(*object.Error); ok {
+        return errors.New(errObj.Message)
+    }
+
+    return vm.push(result)
+}
+
+func (vm *VM) globalName(index int) string {
+    if index < len(vm.globalNames) && vm.globalNames[index] != "" {
+        return vm.globalNames[index]
+    }
+
+    return fmt.Sprintf("global %d", index)
+}
+
+// iterNext advances the iterator on top of the stack and pushes the loop
+// variables for its next element, the value last. When the iterator is
+// exhausted it jumps to pos instead.
+func (vm *VM) iterNext(pos int, numVars int) error {
+    iterator, ok := vm.stack[vm.sp-1].(*object.Iterator)
+    if !ok {
+        return fmt.Errorf("not an iterator: %s", object.TypeName(vm.stack[vm.sp-1]))
+    }
+
+    key, value, ok := iterator.Next()
+    if !ok {
+        vm.currentFrame().ip = pos - 1
+        return nil
+    }
+
+    if numVars == 2 {
+        if err := vm.push(key); err != nil {
+            return err
+        }
+    } else if iterator.SingleKey {
+        value = key
+    }
+
+    return vm.push(value)
+}
+
+func (vm *VM) buildHash(startIndex, endIndex int) (object.Object, error) {
+    hash := object.NewHash()
+
+    for i := startIndex; i < endIndex; i += 2 {
+        key := vm.stack[i]
+        value := vm.stack[i+1]
+
+        hashKey, ok := key.(object.Hashable)
+        if !ok {
+            return nil, fmt.Errorf("unusable as hash key: %s", object.TypeName(key))
+        }
+
+        hash.Set(hashKey, value)
+    }
+
+    return hash, nil
+}
+
+// spreadArguments holds the values of a spread argument on the stack until
+// OpCallArgs expands them.
+type spreadArguments struct {
+    values []object.Object
+}
+
+func (s *spreadArguments) Type() object.ObjectType { return "SPREAD" }
+func (s *spreadArguments) Inspect() string { return "..." }
+
+// executeCallArgs turns the arguments of a call with spread or named
+// arguments into a plain argument list on the stack and calls the function.
+func (vm *VM) executeCallArgs(numPositional, numNamed int) error {
+    named := make([]evaluator.NamedArgument, numNamed)
+    namedStart := vm.sp - 2*numNamed
+    for i := range named {
+        name := vm.stack[namedStart+2*i].(*object.String)
+        named[i] = evaluator.NamedArgument{Name: name.Value, Value: vm.stack[namedStart+2*i+1]}
+    }
+
+    args := []object.Object{}
+    for _, arg := range vm.stack[namedStart-numPositional : namedStart] {
+        if spread, ok := arg.(*spreadArguments); ok {
+            args = append(args, spread.values...)
+        } else {
+            args = append(args, arg)
+        }
+    }
+
+    vm.sp = namedStart - numPositional
+    for _, arg := range args {
+        if err := vm.push(arg); err != nil {
+            return err
+        }
+    }
+
+    return vm.executeCall(len(args), named)
+}
+
+func (vm *VM) executeCall(numArgs int, named []evaluator.NamedArgument) error {
+    callee := vm.stack[vm.sp-1-numArgs]
+
+    switch callee := callee.(type) {
+    case *object.Closure:
+        return vm.callClosure(callee, numArgs, named)
+    case *object.Builtin:
+        if len(named) > 0 {
+            return errors.New("builtin functions do not take named arguments")
+        }
+        return vm.callBuiltin(callee, numArgs)
+    default:
+        return fmt.Errorf("not a function %s", object.TypeName(callee))
+    }
+}
+
+func (vm *VM) callClosure(cl *object.Closure, numArgs int, named []evaluator.NamedArgument) error {
+    fn := cl.Fn
+    if len(named) > 0 || fn.Rest || numArgs != fn.NumParameters {
+        // the parameters the caller left out are pushed as nil, so the
+        // function's prologue evaluates their defaults
+        args := make([]object.Object, numArgs)
+        copy(args, vm.stack[vm.sp-numArgs:vm.sp])
+
+        values, err := evaluator.BindArguments(fn.Name, fn.LocalNames[:fn.NumParameters], fn.NumDefaults, fn.Rest, args, named)
+        if err != nil {
+            return errors.New(err.Message)
+        }
+
+        vm.sp -= numArgs
+        for _, v := range values {
+            if err := vm.push(v); err != nil {
+                return err
+            }
+        }
+        numArgs = len(values)
+    }
+
+    frame := NewFrame(cl, vm.sp-numArgs)
+    if err := vm.pushFrame(frame); err != nil {
+        return err
+    }
+
+    vm.sp = frame.basePointer + cl.Fn.NumLocals
+    if err := vm.growStack(vm.sp); err != nil {
+        return err
+    }
+
+    for i := frame.basePointer + numArgs; i < vm.sp; i++ {
+        vm.stack[i] = nil
+    }
+
+    return nil
+}
+
+// growStack makes room for size slots on the stack, doubling it as needed
+// up to MaxStackSize.
+func (vm *VM) growStack(size int) error {
+    if size <= len(vm.stack) {
+        return nil
+    }
+    if size > MaxStackSize {
+        return errors.New("stack overflow")
+    }
+
+    newSize := len(vm.stack)
+    for newSize < size {
+        newSize *= 2
+    }
+    if newSize > MaxStackSize {
+        newSize = MaxStackSize
+    }
+
+    stack := make([]object.Object, newSize)
+    copy(stack, vm.stack)
+    vm.stack = stack
+
+    return nil
+}
+
+func (vm *VM) callBuiltin(builtin *object.Builtin, numArgs int) error {
+    args := vm.stack[vm.sp-numArgs : vm.sp]
+
+    result := builtin.Fn(vm.options.Out(), args...)
+    vm.sp = vm.sp - numArgs - 1
+
+    if result == nil {
+        return vm.push(Null)
+    }
+
+    return vm.pushResult(result)
+}
+
+func (vm *VM) pushClosure(constIndex int, numFree int) error {
+    constant := vm.constants[constIndex]
+    function, ok := constant.(*object.CompiledFunction)
+    if !ok {
+        return fmt.Errorf("not a function: %+v", constant)
+    }
+
+    free := make([]object.Object, numFree)
+    for i := 0; i < numFree; i++ {
+        free[i] = vm.stack[vm.sp-numFree+i]
+    }
+    vm.sp = vm.sp - numFree
+
+    return vm.push(&object.Closure{Fn: function, Free: free})
+}
@@ -0,0 +1,76 @@
+package vm
+
+import (
+	"bytes"
+	"interpreter/compiler"
+	"interpreter/lexer"
+	"interpreter/object"
+	"interpreter/parser"
+	"interpreter/spectest"
+	"testing"
+)
+
+func TestSpec(t *testing.T) {
+    spectest.Run(t, runVm)
+}
+
+func TestStackOverflow(t *testing.T) {
+    spectest.RunCases(t, runVm, object.Options{}, []spectest.Case{
+        {Input: "let f = fn() { f() }; f()", Expected: spectest.Error("stack overflow")},
+    })
+}
+
+func TestFunctionObject(t *testing.T) {
+    result, err := runVm("fn(x) { x + 2 };", object.Options{})
+    if err != nil {
+        t.Fatalf("vm error: %s", err)
+    }
+
+    closure, ok := result.(*object.Closure)
+    if !ok {
+        t.Fatalf("Expected closure got %T", result)
+    }
+
+    if closure.Fn.NumParameters != 1 {
+        t.Errorf("Expected 1 paramter got %d", closure.Fn.NumParameters)
+    }
+}
+
+func TestPutsOutput(t *testing.T) {
+    program := parser.New(lexer.New(`puts("hello", 1); let f = fn() { puts([2]) }; f()`)).ParseProgram()
+
+    comp := compiler.New()
+    if err := comp.Compile(program); err != nil {
+        t.Fatalf("compiler error: %s", err)
+    }
+
+    var out bytes.Buffer
+    machine := NewWithOptions(comp.Bytecode(), object.Options{Output: &out})
+    if err := machine.Run(); err != nil {
+        t.Fatalf("vm error: %s", err)
+    }
+
+    if out.String() != "hello\n1\n[2]\n" {
+        t.Errorf("wrong output. got=%q", out.String())
+    }
+}
+
+func runVm(input string, options object.Options) (object.Object, error) {
+    p := parser.New(lexer.New(input))
+    program := p.ParseProgram()
+    if len(p.Errors()) != 0 {
+        return nil, p.Errors()[0]
+    }
+
+    comp := compiler.New()
+    if err := comp.Compile(program); err != nil {
+        return nil, err
+    }
+
+    machine := NewWithOptions(comp.Bytecode(), options)
+    if err := machine.Run(); err != nil {
+        return nil, err
+    }
+
+    return machine.LastPoppedStackElem(), nil
+}